@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/internal/conformity/rules"
+)
+
+const usage = `Usage:
+  gitlab-mr-conform                          start the bot
+  gitlab-mr-conform validate-config [FILE]   validate a .mr-conform.yaml file (default: .mr-conform.yaml)
+  gitlab-mr-conform schema                   print the JSON Schema of .mr-conform.yaml
+`
+
+// runCommand executes a CLI subcommand and returns the process exit code
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	switch args[0] {
+	case "validate-config":
+		return validateConfig(args[1:], stdout, stderr)
+	case "schema":
+		return printSchema(stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+// validateConfig validates repository configuration files and reports every issue found
+func validateConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{".mr-conform.yaml"}
+	}
+
+	exitCode := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			exitCode = 1
+			continue
+		}
+
+		issues, err := config.ValidateFile(data, rules.ConfigTypes())
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			exitCode = 1
+			continue
+		}
+
+		if len(issues) == 0 {
+			fmt.Fprintf(stdout, "%s: OK\n", file)
+			continue
+		}
+
+		for _, issue := range issues {
+			fmt.Fprintf(stdout, "%s: %s\n", file, issue)
+		}
+		exitCode = 1
+	}
+
+	return exitCode
+}
+
+// printSchema writes the JSON Schema of .mr-conform.yaml
+func printSchema(stdout, stderr io.Writer) int {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(config.RulesSchema(rules.ConfigTypes())); err != nil {
+		fmt.Fprintf(stderr, "failed to generate schema: %v\n", err)
+		return 1
+	}
+	return 0
+}
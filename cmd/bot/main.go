@@ -7,18 +7,25 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"gitlab-mr-conformity-bot/internal/config"
 	"gitlab-mr-conformity-bot/internal/conformity"
 	"gitlab-mr-conformity-bot/internal/gitlab"
+	"gitlab-mr-conformity-bot/internal/metrics"
 	"gitlab-mr-conformity-bot/internal/queue"
+	"gitlab-mr-conformity-bot/internal/redisclient"
 	"gitlab-mr-conformity-bot/internal/server"
 	"gitlab-mr-conformity-bot/internal/storage"
+	"gitlab-mr-conformity-bot/internal/tracing"
 	"gitlab-mr-conformity-bot/pkg/logger"
 )
 
 func main() {
+	// Run CLI subcommands without starting the bot
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	// Initialize logger
 	log := logger.New()
 
@@ -32,23 +39,69 @@ func main() {
 
 	log.SetLevel(cfg.Server.LogLevel)
 
-	// Initialize Redis queue manager
+	if err := conformity.NewRuleBuilder(cfg.Integrations).Validate(cfg.Rules); err != nil {
+		log.Fatal("Invalid rules configuration", "error", err)
+	}
+
+	// Initialize tracing, spans are only exported when enabled
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Enabled:     cfg.Tracing.Enabled,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	}, Version)
+	if err != nil {
+		log.Fatal("Failed to initialize tracing", "error", err)
+	}
+
+	// Redis queue settings
 	queueConfig := &queue.Config{
-		RedisHost:          cfg.Queue.Redis.Host,
-		RedisPassword:      cfg.Queue.Redis.Password,
-		RedisDB:            cfg.Queue.Redis.DB,
+		Redis:              redisConfig(cfg.Queue.Redis),
 		QueuePrefix:        "gitlab:mr:queue",
 		LockPrefix:         "gitlab:mr:lock",
 		ProcessingPrefix:   "gitlab:mr:processing",
-		DefaultLockTTL:     cfg.Queue.Queue.LockTTL,            //10 * time.Second,
-		MaxRetries:         cfg.Queue.Queue.MaxRetries,         //3,
+		StatsPrefix:        "gitlab:mr:stats",
+		EventPrefix:        "gitlab:mr:event",
+		DeadPrefix:         "gitlab:mr:dead",
+		MaxDeadJobs:        cfg.Queue.Queue.MaxDeadJobs,
+		DebouncePrefix:     "gitlab:mr:debounce",
+		Debounce:           cfg.Queue.Queue.Debounce,
+		DefaultLockTTL:     cfg.Queue.Queue.LockTTL, //10 * time.Second,
+		VisibilityTimeout:  cfg.Queue.Queue.VisibilityTimeout,
+		MaxRetries:         cfg.Queue.Queue.MaxRetries, //3,
+		RetryBackoffMin:    cfg.Queue.Queue.RetryBackoffMin,
+		RetryBackoffMax:    cfg.Queue.Queue.RetryBackoffMax,
 		ProcessingInterval: cfg.Queue.Queue.ProcessingInterval, // 100 * time.Milisecond,
+		Workers:            cfg.Queue.Queue.Workers,
+		DrainTimeout:       cfg.Queue.Queue.DrainTimeout,
 	}
 
-	queueManager := queue.NewQueueManager(queueConfig, log)
+	// Initialize the queue only if enabled
+	var jobQueue queue.JobQueue
+	if cfg.Queue.Enabled {
+		jobQueue, err = newJobQueue(cfg, queueConfig, log)
+		if err != nil {
+			log.Fatal("Failed to initialize queue", "backend", cfg.Queue.Backend, "error", err)
+		}
+		defer jobQueue.Close()
+		log.Info("Initialized queue", "backend", cfg.Queue.Backend)
+
+		if cfg.Metrics.Enabled {
+			if err := metrics.RegisterQueueCollector(jobQueue); err != nil {
+				log.Fatal("Failed to register queue metrics", "error", err)
+			}
+		}
+	}
 
 	// Initialize GitLab client
-	gitlabClient, err := gitlab.NewClient(cfg.GitLab.Token, cfg.GitLab.BaseURL, cfg.GitLab.Insecure)
+	gitlabClient, err := gitlab.NewClient(cfg.GitLab.Token, cfg.GitLab.BaseURL, cfg.GitLab.Insecure, cfg.GitLab.Timeout, gitlab.RateLimitConfig{
+		MaxRetries:        cfg.GitLab.RateLimit.MaxRetries,
+		RetryWaitMin:      cfg.GitLab.RateLimit.RetryWaitMin,
+		RetryWaitMax:      cfg.GitLab.RateLimit.RetryWaitMax,
+		RequestsPerSecond: cfg.GitLab.RateLimit.RequestsPerSecond,
+		MaxConcurrency:    cfg.GitLab.RateLimit.MaxConcurrency,
+	})
 	if err != nil {
 		log.Fatal("Failed to create GitLab client", "error", err)
 	}
@@ -56,13 +109,18 @@ func main() {
 	log.Info("Connected to GitLab server", "server", cfg.GitLab.BaseURL)
 
 	// Initialize storage
-	store := storage.NewMemoryStorage()
+	store, err := newStorage(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage", "backend", cfg.Storage.Backend, "error", err)
+	}
+	defer store.Close()
+	log.Info("Initialized storage", "backend", cfg.Storage.Backend)
 
 	// Initialize conformity checker
-	checker := conformity.NewChecker(cfg.Rules, gitlabClient, log, cfg.Integrations)
+	checker := conformity.NewChecker(cfg, gitlabClient, store, log)
 
 	// Initialize HTTP server
-	srv := server.NewServer(cfg, gitlabClient, checker, store, log, queueManager)
+	srv := server.NewServer(cfg, gitlabClient, checker, store, log, jobQueue)
 
 	// Create context for graceful shutdown
 	c, cancel := context.WithCancel(context.Background())
@@ -70,7 +128,7 @@ func main() {
 
 	// Start the background job processor only if queue is enabled
 	if cfg.Queue.Enabled {
-		go srv.StartProcessor(c)
+		srv.StartProcessor(c)
 	} else {
 		log.Info("Queue processing disabled, webhooks will be processed synchronously")
 	}
@@ -95,12 +153,106 @@ func main() {
 	log.Info("Shutting down server...")
 
 	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Queue.Queue.DrainTimeout)
+	defer shutdownCancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Error("Server forced to shutdown", "error", err)
+	}
+
+	// Let queued jobs in flight finish; those still running at the deadline are cancelled
+	if cfg.Queue.Enabled {
+		if err := srv.StopProcessor(shutdownCtx); err != nil {
+			log.Error("Queue processor did not drain in time", "error", err)
+		}
+	} else if err := srv.DrainChecks(shutdownCtx); err != nil {
+		log.Error("Webhook checks did not drain in time", "error", err)
+	}
 
-	if err := httpServer.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown", "error", err)
+	// Cancel any remaining in-flight GitLab or Asana calls
+	cancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error("Failed to flush traces", "error", err)
 	}
 
 	log.Info("Server exited")
 }
+
+// newStorage creates the configured storage backend
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.Storage.Backend {
+	case "", "memory":
+		return storage.NewMemoryStorage(), nil
+	case "bolt":
+		return storage.NewBoltStorage(cfg.Storage.Bolt.Path, cfg.Storage.Bolt.CleanupInterval)
+	case "redis":
+		store, err := storage.NewRedisStorage(storage.RedisConfig{
+			Redis:     redisConfig(cfg.Queue.Redis),
+			KeyPrefix: cfg.Storage.Redis.KeyPrefix,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Ping(); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (available: memory, bolt, redis)", cfg.Storage.Backend)
+	}
+}
+
+// newJobQueue creates the configured queue backend
+func newJobQueue(cfg *config.Config, queueConfig *queue.Config, log *logger.Logger) (queue.JobQueue, error) {
+	switch cfg.Queue.Backend {
+	case "", "redis":
+		return queue.NewQueueManager(queueConfig, log)
+	case "memory":
+		return queue.NewMemoryQueue(&queue.MemoryConfig{
+			MaxJobs:         cfg.Queue.Memory.MaxJobs,
+			JournalPath:     cfg.Queue.Memory.JournalPath,
+			Debounce:        cfg.Queue.Queue.Debounce,
+			MaxRetries:      cfg.Queue.Queue.MaxRetries,
+			RetryBackoffMin: cfg.Queue.Queue.RetryBackoffMin,
+			RetryBackoffMax: cfg.Queue.Queue.RetryBackoffMax,
+			MaxDeadJobs:     cfg.Queue.Queue.MaxDeadJobs,
+			Workers:         cfg.Queue.Queue.Workers,
+			DrainTimeout:    cfg.Queue.Queue.DrainTimeout,
+		}, log)
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q (available: redis, memory)", cfg.Queue.Backend)
+	}
+}
+
+// redisConfig converts the Redis settings shared by the queue and the storage
+func redisConfig(cfg config.RedisConfig) redisclient.Config {
+	return redisclient.Config{
+		Addr:             cfg.Host,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		MasterName:       cfg.Sentinel.MasterName,
+		SentinelAddrs:    cfg.Sentinel.Addrs,
+		SentinelUsername: cfg.Sentinel.Username,
+		SentinelPassword: cfg.Sentinel.Password,
+		Cluster:          cfg.Cluster.Enabled,
+		ClusterAddrs:     cfg.Cluster.Addrs,
+		TLS: redisclient.TLSConfig{
+			Enabled:            cfg.TLS.Enabled,
+			CAFile:             cfg.TLS.CAFile,
+			CertFile:           cfg.TLS.CertFile,
+			KeyFile:            cfg.TLS.KeyFile,
+			ServerName:         cfg.TLS.ServerName,
+			InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+		},
+		PoolSize:     cfg.Pool.Size,
+		MinIdleConns: cfg.Pool.MinIdleConns,
+		DialTimeout:  cfg.Pool.DialTimeout,
+		ReadTimeout:  cfg.Pool.ReadTimeout,
+		WriteTimeout: cfg.Pool.WriteTimeout,
+		PoolTimeout:  cfg.Pool.PoolTimeout,
+		IdleTimeout:  cfg.Pool.IdleTimeout,
+		MaxConnAge:   cfg.Pool.MaxConnAge,
+	}
+}
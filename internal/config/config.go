@@ -1,10 +1,13 @@
 package config
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"gitlab-mr-conformity-bot/internal/gitlab"
 	"gitlab-mr-conformity-bot/pkg/logger"
+	"reflect"
 	"strings"
 	"time"
 
@@ -16,6 +19,10 @@ type Config struct {
 		Port     int    `mapstructure:"port"`
 		Host     string `mapstructure:"host"`
 		LogLevel string `mapstructure:"log_level"`
+		// CheckTimeout bounds a complete conformity check of a merge request
+		CheckTimeout time.Duration `mapstructure:"check_timeout"`
+		// AdminToken protects the /api/v1/admin, /api/v1/history and /api/v1/reports endpoints; they are disabled when empty
+		AdminToken string `mapstructure:"admin_token"`
 	} `mapstructure:"server"`
 
 	GitLab struct {
@@ -23,6 +30,9 @@ type Config struct {
 		BaseURL     string `mapstructure:"base_url"`
 		SecretToken string `mapstructure:"secret_token"`
 		Insecure    bool   `mapstructure:"insecure"`
+		// Timeout bounds every single GitLab API request
+		Timeout   time.Duration   `mapstructure:"timeout"`
+		RateLimit RateLimitConfig `mapstructure:"rate_limit"`
 	} `mapstructure:"gitlab"`
 
 	Rules RulesConfig `mapstructure:"rules"`
@@ -30,27 +40,197 @@ type Config struct {
 	Queue QueueConfig `mapstructure:"queue"`
 
 	Integrations IntegrationsConfig `mapstructure:"integrations"`
+
+	Inheritance InheritanceConfig `mapstructure:"inheritance"`
+
+	Preview PreviewConfig `mapstructure:"preview"`
+
+	Cache CacheConfig `mapstructure:"cache"`
+
+	Storage StorageConfig `mapstructure:"storage"`
+
+	History HistoryConfig `mapstructure:"history"`
+
+	Metrics MetricsConfig `mapstructure:"metrics"`
+
+	Tracing TracingConfig `mapstructure:"tracing"`
+
+	Webhook WebhookConfig `mapstructure:"webhook"`
+}
+
+// RateLimitConfig holds retry, throttling and concurrency settings for GitLab API requests
+type RateLimitConfig struct {
+	MaxRetries   int           `mapstructure:"max_retries"`
+	RetryWaitMin time.Duration `mapstructure:"retry_wait_min"`
+	RetryWaitMax time.Duration `mapstructure:"retry_wait_max"`
+	// RequestsPerSecond caps the request rate; 0 relies on GitLab's RateLimit-* headers only
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	// MaxConcurrency caps the number of GitLab requests in flight; 0 means unlimited
+	MaxConcurrency int `mapstructure:"max_concurrency"`
 }
 
-// QueueConfig holds Redis queue configuration
+// InheritanceConfig holds group-level configuration inheritance settings
+type InheritanceConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// ConfigProject is the name of the project in each group holding the group's .mr-conform.yaml
+	ConfigProject string `mapstructure:"config_project"`
+}
+
+// StorageConfig selects where check results, caches and history are kept
+type StorageConfig struct {
+	// Backend is one of memory, bolt or redis
+	Backend string           `mapstructure:"backend"`
+	Bolt    BoltConfig       `mapstructure:"bolt"`
+	Redis   RedisStoreConfig `mapstructure:"redis"`
+}
+
+// BoltConfig holds settings of the embedded BoltDB storage
+type BoltConfig struct {
+	Path string `mapstructure:"path"`
+	// CleanupInterval is how often expired values are removed from the file
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+}
+
+// RedisStoreConfig holds settings of the Redis storage, which connects with queue.redis
+type RedisStoreConfig struct {
+	KeyPrefix string `mapstructure:"key_prefix"`
+}
+
+// WebhookConfig holds settings for receiving GitLab webhooks
+type WebhookConfig struct {
+	// DedupTTL is how long delivery UUIDs are remembered to ignore retried
+	// deliveries; 0 disables deduplication
+	DedupTTL time.Duration `mapstructure:"dedup_ttl"`
+}
+
+// TracingConfig holds OpenTelemetry trace export settings
+type TracingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Endpoint is the host:port of an OTLP/HTTP collector, OTEL_EXPORTER_OTLP_* variables apply when empty
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// MetricsConfig holds settings of the Prometheus /metrics endpoint
+type MetricsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// HistoryConfig holds settings for recording every check run in the storage
+type HistoryConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxEntries caps the records kept per project, dropping the oldest; 0 keeps all
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+// CacheConfig holds settings for reusing check results of unchanged merge requests
+type CacheConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TTL bounds how long a result is reused, as changes to CODEOWNERS on the
+	// target branch or to project membership do not invalidate it
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// PreviewConfig holds settings for previewing configuration changed by a merge request
+type PreviewConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// QueueConfig holds queue configuration
 type QueueConfig struct {
-	Enabled bool          `mapstructure:"enabled"`
-	Redis   RedisConfig   `mapstructure:"redis"`
-	Queue   QueueSettings `mapstructure:"queue"`
+	Enabled bool `mapstructure:"enabled"`
+	// Backend is one of redis or memory; memory runs a single replica without Redis
+	Backend string            `mapstructure:"backend"`
+	Redis   RedisConfig       `mapstructure:"redis"`
+	Memory  MemoryQueueConfig `mapstructure:"memory"`
+	Queue   QueueSettings     `mapstructure:"queue"`
+}
+
+// MemoryQueueConfig holds settings of the in-process queue
+type MemoryQueueConfig struct {
+	// MaxJobs bounds the queued jobs; webhooks are rejected with 503 beyond it
+	MaxJobs int `mapstructure:"max_jobs"`
+	// JournalPath keeps queued jobs in a file across restarts; empty keeps them in memory only
+	JournalPath string `mapstructure:"journal_path"`
 }
 
 // RedisConfig holds Redis connection settings
 type RedisConfig struct {
-	Host     string `mapstructure:"host"`
-	Password string `mapstructure:"password"`
-	DB       int    `mapstructure:"db"`
+	Host string `mapstructure:"host"`
+	// Username authenticates with Redis ACLs; empty uses the default user
+	Username string              `mapstructure:"username"`
+	Password string              `mapstructure:"password"`
+	DB       int                 `mapstructure:"db"`
+	Sentinel RedisSentinelConfig `mapstructure:"sentinel"`
+	Cluster  RedisClusterConfig  `mapstructure:"cluster"`
+	TLS      RedisTLSConfig      `mapstructure:"tls"`
+	Pool     RedisPoolConfig     `mapstructure:"pool"`
+}
+
+// RedisSentinelConfig connects to the master monitored by Redis Sentinel, when MasterName is set
+type RedisSentinelConfig struct {
+	MasterName string   `mapstructure:"master_name"`
+	Addrs      []string `mapstructure:"addrs"`
+	Username   string   `mapstructure:"username"`
+	Password   string   `mapstructure:"password"`
+}
+
+// RedisClusterConfig connects to a Redis Cluster
+type RedisClusterConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Addrs are seed nodes of the cluster; host is used when empty
+	Addrs []string `mapstructure:"addrs"`
+}
+
+// RedisTLSConfig holds TLS settings of Redis connections
+type RedisTLSConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// CAFile verifies the server with a custom CA instead of the system ones
+	CAFile string `mapstructure:"ca_file"`
+	// CertFile and KeyFile hold a client certificate for mutual TLS
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	ServerName         string `mapstructure:"server_name"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+// RedisPoolConfig tunes the connection pool; zero values keep the client defaults
+type RedisPoolConfig struct {
+	Size         int           `mapstructure:"size"`
+	MinIdleConns int           `mapstructure:"min_idle_conns"`
+	DialTimeout  time.Duration `mapstructure:"dial_timeout"`
+	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	PoolTimeout  time.Duration `mapstructure:"pool_timeout"`
+	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	MaxConnAge   time.Duration `mapstructure:"max_conn_age"`
 }
 
 // QueueSettings holds queue behavior settings
 type QueueSettings struct {
 	ProcessingInterval time.Duration `mapstructure:"processing_interval"`
 	MaxRetries         int           `mapstructure:"max_retries"`
-	LockTTL            time.Duration `mapstructure:"lock_ttl"`
+	// RetryBackoffMin is the delay before the first retry of a failed job; it
+	// doubles with every further attempt up to RetryBackoffMax
+	RetryBackoffMin time.Duration `mapstructure:"retry_backoff_min"`
+	RetryBackoffMax time.Duration `mapstructure:"retry_backoff_max"`
+	LockTTL         time.Duration `mapstructure:"lock_ttl"`
+	// VisibilityTimeout is how long a job stays in processing without a lock
+	// heartbeat before it is requeued; it must exceed LockTTL
+	VisibilityTimeout time.Duration `mapstructure:"visibility_timeout"`
+	// Debounce waits for a merge request to receive no events for this long
+	// before checking it; 0 checks as soon as a job is queued
+	Debounce time.Duration `mapstructure:"debounce"`
+	// Workers is the number of merge requests processed in parallel per replica
+	Workers int `mapstructure:"workers"`
+	// DrainTimeout bounds the graceful shutdown, including waiting for jobs in flight
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// MaxDeadJobs caps the dead-letter queue of jobs that failed their last attempt; 0 keeps all
+	MaxDeadJobs int `mapstructure:"max_dead_jobs"`
+	// NotifyDeadJobs posts a note on merge requests whose check could not be completed
+	NotifyDeadJobs bool `mapstructure:"notify_dead_jobs"`
 }
 
 // Integrations settings
@@ -61,6 +241,8 @@ type IntegrationsConfig struct {
 // AsanaConfig holds Asana integration settings
 type AsanaConfig struct {
 	APIToken string `mapstructure:"api_token"`
+	// Timeout bounds every single Asana API request
+	Timeout time.Duration `mapstructure:"timeout"`
 }
 
 type RulesConfig struct {
@@ -70,10 +252,15 @@ type RulesConfig struct {
 	Commits     CommitsConfig     `mapstructure:"commits"`
 	Approvals   ApprovalsConfig   `mapstructure:"approvals"`
 	Squash      SquashConfig      `mapstructure:"squash"`
+
+	// Extra holds configuration of rules registered outside the built-in set,
+	// keyed by their rule name
+	Extra map[string]interface{} `mapstructure:",remain"`
 }
 
 type TitleConfig struct {
 	Enabled        bool                 `mapstructure:"enabled"`
+	Severity       string               `mapstructure:"severity" validate:"severity"`
 	MinLength      int                  `mapstructure:"min_length"`
 	MaxLength      int                  `mapstructure:"max_length"`
 	Conventional   ConventionalConfig   `mapstructure:"conventional"`
@@ -84,6 +271,7 @@ type TitleConfig struct {
 
 type DescriptionConfig struct {
 	Enabled         bool                 `mapstructure:"enabled"`
+	Severity        string               `mapstructure:"severity" validate:"severity"`
 	Required        bool                 `mapstructure:"required"`
 	MinLength       int                  `mapstructure:"min_length"`
 	RequireTemplate bool                 `mapstructure:"require_template"`
@@ -93,12 +281,14 @@ type DescriptionConfig struct {
 
 type BranchConfig struct {
 	Enabled         bool     `mapstructure:"enabled"`
+	Severity        string   `mapstructure:"severity" validate:"severity"`
 	AllowedPrefixes []string `mapstructure:"allowed_prefixes"`
 	ForbiddenNames  []string `mapstructure:"forbidden_names"`
 }
 
 type CommitsConfig struct {
 	Enabled      bool                 `mapstructure:"enabled"`
+	Severity     string               `mapstructure:"severity" validate:"severity"`
 	MaxLength    int                  `mapstructure:"max_length"`
 	Conventional ConventionalConfig   `mapstructure:"conventional"`
 	Jira         JiraConfig           `mapstructure:"jira"`
@@ -106,21 +296,56 @@ type CommitsConfig struct {
 }
 
 type ApprovalsConfig struct {
-	Enabled                 bool `mapstructure:"enabled"`
-	MinCount                int  `mapstructure:"min_count"`
-	UseCodeowners           bool `mapstructure:"use_codeowners"`
-	ExcludeCreatorFromCount bool `mapstructure:"exclude_creator_from_count"`
+	Enabled                 bool   `mapstructure:"enabled"`
+	Severity                string `mapstructure:"severity" validate:"severity"`
+	MinCount                int    `mapstructure:"min_count"`
+	UseCodeowners           bool   `mapstructure:"use_codeowners"`
+	UseApprovalRules        bool   `mapstructure:"use_approval_rules"`
+	ExcludeCreatorFromCount bool   `mapstructure:"exclude_creator_from_count"`
 }
 
 type SquashConfig struct {
 	Enabled          bool     `mapstructure:"enabled"`
-	EnforceBranches  []string `mapstructure:"enforce_branches"`
-	DisallowBranches []string `mapstructure:"disallow_branches"`
+	Severity         string   `mapstructure:"severity" validate:"severity"`
+	EnforceBranches  []string `mapstructure:"enforce_branches" validate:"glob"`
+	DisallowBranches []string `mapstructure:"disallow_branches" validate:"glob"`
+}
+
+// Severities returns the configured severity of every rule keyed by rule name.
+// Rules without an explicit severity are omitted.
+func (rc RulesConfig) Severities() map[string]string {
+	severities := make(map[string]string)
+
+	builtin := map[string]string{
+		"title":       rc.Title.Severity,
+		"description": rc.Description.Severity,
+		"branch":      rc.Branch.Severity,
+		"commits":     rc.Commits.Severity,
+		"approvals":   rc.Approvals.Severity,
+		"squash":      rc.Squash.Severity,
+	}
+	for key, severity := range builtin {
+		if severity != "" {
+			severities[key] = severity
+		}
+	}
+
+	for key, raw := range rc.Extra {
+		values, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if severity, ok := values["severity"].(string); ok && severity != "" {
+			severities[key] = severity
+		}
+	}
+
+	return severities
 }
 
 type ConventionalConfig struct {
 	Types  []string `mapstructure:"types"`
-	Scopes []string `mapstructure:"scopes"`
+	Scopes []string `mapstructure:"scopes" validate:"regexp"`
 }
 
 type JiraConfig struct {
@@ -135,6 +360,8 @@ type AsanaValidatorConfig struct {
 // ConfigLoader handles loading and merging configurations
 type ConfigLoader struct {
 	defaultConfig RulesConfig
+	inheritance   InheritanceConfig
+	ruleTypes     map[string]reflect.Type
 	gitlabClient  *gitlab.Client
 	logger        *logger.Logger
 }
@@ -150,12 +377,60 @@ func Load() (*Config, error) {
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.log_level", "INFO")
 	viper.SetDefault("gitlab.base_url", "https://gitlab.com")
+	viper.SetDefault("server.check_timeout", "2m")
 	viper.SetDefault("gitlab.insecure", false)
+	viper.SetDefault("gitlab.timeout", "30s")
+	viper.SetDefault("gitlab.rate_limit.max_retries", 3)
+	viper.SetDefault("gitlab.rate_limit.retry_wait_min", "500ms")
+	viper.SetDefault("gitlab.rate_limit.retry_wait_max", "30s")
+	viper.SetDefault("gitlab.rate_limit.requests_per_second", 0)
+	viper.SetDefault("gitlab.rate_limit.max_concurrency", 4)
+	viper.SetDefault("integrations.asana.timeout", "5s")
 	// Queue
 	viper.SetDefault("queue.enabled", false)
+	viper.SetDefault("queue.backend", "redis")
+	viper.SetDefault("queue.memory.max_jobs", 1000)
+	viper.SetDefault("queue.memory.journal_path", "")
+	viper.SetDefault("queue.redis.sentinel.master_name", "")
+	viper.SetDefault("queue.redis.cluster.enabled", false)
+	viper.SetDefault("queue.redis.tls.enabled", false)
+	viper.SetDefault("queue.redis.pool.size", 0)
 	viper.SetDefault("queue.queue.lock_ttl", "10s")
+	viper.SetDefault("queue.queue.visibility_timeout", "1m")
 	viper.SetDefault("queue.queue.max_retries", 3)
+	viper.SetDefault("queue.queue.retry_backoff_min", "5s")
+	viper.SetDefault("queue.queue.retry_backoff_max", "5m")
 	viper.SetDefault("queue.queue.processing_interval", "100ms")
+	viper.SetDefault("queue.queue.debounce", "2s")
+	viper.SetDefault("queue.queue.workers", 4)
+	viper.SetDefault("queue.queue.drain_timeout", "30s")
+	viper.SetDefault("queue.queue.max_dead_jobs", 1000)
+	viper.SetDefault("queue.queue.notify_dead_jobs", false)
+	// Inheritance
+	viper.SetDefault("inheritance.enabled", false)
+	viper.SetDefault("inheritance.config_project", "mr-conform-config")
+	// Preview
+	viper.SetDefault("preview.enabled", false)
+	// Storage
+	viper.SetDefault("storage.backend", "memory")
+	viper.SetDefault("storage.bolt.path", "mr-conform.db")
+	viper.SetDefault("storage.bolt.cleanup_interval", "10m")
+	viper.SetDefault("storage.redis.key_prefix", "gitlab:mr:storage:")
+	// Webhook
+	viper.SetDefault("webhook.dedup_ttl", "24h")
+	// Tracing
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.insecure", false)
+	viper.SetDefault("tracing.service_name", "gitlab-mr-conform")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	// Metrics
+	viper.SetDefault("metrics.enabled", true)
+	// History
+	viper.SetDefault("history.enabled", true)
+	viper.SetDefault("history.max_entries", 10000)
+	// Cache
+	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.ttl", "10m")
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
@@ -168,7 +443,9 @@ func Load() (*Config, error) {
 	_ = viper.BindEnv("gitlab.token")
 	_ = viper.BindEnv("gitlab.secrettoken")
 	_ = viper.BindEnv("gitlab.base_url")
+	_ = viper.BindEnv("server.admin_token")
 	_ = viper.BindEnv("queue.redis.password")
+	_ = viper.BindEnv("queue.redis.sentinel.password")
 	_ = viper.BindEnv("integrations.asana.api_token")
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
@@ -182,68 +459,209 @@ func Load() (*Config, error) {
 }
 
 // NewConfigLoader creates a new configuration loader
-func NewConfigLoader(defaultConfig RulesConfig, client *gitlab.Client, log *logger.Logger) *ConfigLoader {
+func NewConfigLoader(defaultConfig RulesConfig, inheritance InheritanceConfig, client *gitlab.Client, log *logger.Logger) *ConfigLoader {
 	return &ConfigLoader{
 		defaultConfig: defaultConfig,
+		inheritance:   inheritance,
 		gitlabClient:  client,
 		logger:        log,
 	}
 }
 
-// LoadConfig loads configuration for a project, trying repository config first, then falling back to default
-func (cl *ConfigLoader) LoadConfig(projectID interface{}) (RulesConfig, error) {
-	repoConfig, err := cl.loadRepositoryConfig(projectID)
+// SetRuleTypes sets the configuration types of additionally registered rules,
+// so their keys are accepted when validating configuration files
+func (cl *ConfigLoader) SetRuleTypes(types map[string]reflect.Type) {
+	cl.ruleTypes = types
+}
+
+// LoadConfig loads the effective rules configuration for a project
+func (cl *ConfigLoader) LoadConfig(ctx context.Context, projectID interface{}) (RulesConfig, error) {
+	effective, err := cl.LoadEffectiveConfig(ctx, projectID)
+	if err != nil {
+		return RulesConfig{}, err
+	}
+	return effective.Rules, nil
+}
+
+// LoadEffectiveConfig merges the bot defaults, the configuration of every parent
+// group (when inheritance is enabled) and the repository configuration
+func (cl *ConfigLoader) LoadEffectiveConfig(ctx context.Context, projectID interface{}) (*EffectiveConfig, error) {
+	return cl.LoadEffectiveConfigAt(ctx, projectID, "")
+}
+
+// LoadEffectiveConfigAt is like LoadEffectiveConfig, but reads the repository
+// configuration at ref instead of the default branch
+func (cl *ConfigLoader) LoadEffectiveConfigAt(ctx context.Context, projectID interface{}, ref string) (*EffectiveConfig, error) {
+	layers := []Layer{{Name: "default", Values: ToMap(cl.defaultConfig)}}
+	var warnings []string
+
+	if cl.inheritance.Enabled {
+		groupLayers, groupWarnings := cl.loadGroupLayers(ctx, projectID)
+		layers = append(layers, groupLayers...)
+		warnings = append(warnings, groupWarnings...)
+	}
+
+	repoLayer, err := cl.loadRepositoryLayer(ctx, projectID, ref)
+	if err != nil {
+		cl.logger.Debug("Skipping repository configuration", "reason", err.Error())
+		var invalid *InvalidConfigError
+		if errors.As(err, &invalid) {
+			warnings = append(warnings, invalid.Error())
+		}
+	} else {
+		layers = append(layers, *repoLayer)
+	}
+
+	// Missing files are skipped, but a cancelled check must not fall back to defaults
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	merged, sources := MergeLayers(layers)
+
+	var rules RulesConfig
+	if err := Decode(merged, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode merged config: %w", err)
+	}
+
+	names := make([]string, 0, len(layers))
+	for _, layer := range layers {
+		names = append(names, layer.Name)
+	}
+	cl.logger.Debug("Loaded effective configuration", "layers", names)
+
+	return &EffectiveConfig{
+		Rules:    rules,
+		Values:   merged,
+		Layers:   names,
+		Sources:  sources,
+		Warnings: warnings,
+	}, nil
+}
+
+// loadGroupLayers loads the configuration of every parent group, top-level group first
+func (cl *ConfigLoader) loadGroupLayers(ctx context.Context, projectID interface{}) ([]Layer, []string) {
+	project, err := cl.gitlabClient.GetProject(ctx, projectID)
 	if err != nil {
-		cl.logger.Debug("Using default configuration", "reason", err.Error())
+		cl.logger.Warn("Failed to get project namespace, skipping group configuration", "error", err)
+		return nil, nil
+	}
+	if project.Namespace == nil || project.Namespace.Kind != "group" {
+		return nil, nil
+	}
+
+	var layers []Layer
+	var warnings []string
+	for _, groupPath := range parentGroups(project.Namespace.FullPath) {
+		configProject := groupPath + "/" + cl.inheritance.ConfigProject
+		if configProject == project.PathWithNamespace {
+			continue
+		}
+
+		cfg, err := cl.gitlabClient.GetConfigFile(ctx, configProject, "")
+		if err != nil {
+			cl.logger.Debug("No group config file found", "group", groupPath, "error", err)
+			continue
+		}
+
+		layerName := "group:" + groupPath
+		values, err := cl.parseLayer(layerName, cfg.Content)
+		if err != nil {
+			cl.logger.Warn("Failed to load group config file, skipping", "group", groupPath, "error", err)
+			warnings = append(warnings, err.Error())
+			continue
+		}
+
+		layers = append(layers, Layer{Name: layerName, Values: values})
 	}
 
-	return cl.selectConfig(repoConfig), nil
+	return layers, warnings
 }
 
-// loadRepositoryConfig attempts to load config from repository, returns nil if not found or invalid
-func (cl *ConfigLoader) loadRepositoryConfig(projectID interface{}) (*RulesConfig, error) {
+// loadRepositoryLayer attempts to load config from repository, returns an error if not found or invalid
+func (cl *ConfigLoader) loadRepositoryLayer(ctx context.Context, projectID interface{}, ref string) (*Layer, error) {
 	// Try to get config file from repository
-	cfg, err := cl.gitlabClient.GetConfigFile(projectID)
+	cfg, err := cl.gitlabClient.GetConfigFile(ctx, projectID, ref)
 	if err != nil {
-		cl.logger.Debug("No config file found in repository, using default config", "error", err)
+		cl.logger.Debug("No config file found in repository", "error", err)
 		return nil, err
 	}
 
+	values, err := cl.parseLayer("repository", cfg.Content)
+	if err != nil {
+		cl.logger.Warn("Failed to load config file from repository, ignoring it", "error", err)
+		return nil, err
+	}
+
+	cl.logger.Debug("Successfully loaded config from repository")
+	return &Layer{Name: "repository", Values: values}, nil
+}
+
+// InvalidConfigError reports a configuration file that was ignored because it
+// could not be parsed or failed validation
+type InvalidConfigError struct {
+	Layer  string
+	Err    error
+	Issues []ValidationIssue
+}
+
+func (e *InvalidConfigError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s configuration ignored: %v", e.Layer, e.Err)
+	}
+
+	issues := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		issues = append(issues, issue.String())
+	}
+	return fmt.Sprintf("%s configuration ignored: %s", e.Layer, strings.Join(issues, "; "))
+}
+
+func (e *InvalidConfigError) Unwrap() error {
+	return e.Err
+}
+
+// parseLayer decodes a base64 encoded .mr-conform.yaml, validates it and returns its rules section
+func (cl *ConfigLoader) parseLayer(name, content string) (map[string]interface{}, error) {
 	// Decode the base64 content
-	decoded, err := base64.StdEncoding.DecodeString(cfg.Content)
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, &InvalidConfigError{Layer: name, Err: fmt.Errorf("failed to decode config: %w", err)}
+	}
+
+	issues, err := ValidateFile(decoded, cl.ruleTypes)
 	if err != nil {
-		cl.logger.Warn("Failed to decode config file from repository, using default config", "error", err)
-		return nil, fmt.Errorf("failed to decode config: %w", err)
+		return nil, &InvalidConfigError{Layer: name, Err: err}
+	}
+	if len(issues) > 0 {
+		return nil, &InvalidConfigError{Layer: name, Issues: issues}
 	}
 
 	// Create a new viper instance to avoid global state conflicts
 	v := viper.New()
 	v.SetConfigType("yaml")
 
-	err = v.ReadConfig(strings.NewReader(string(decoded)))
-	if err != nil {
-		cl.logger.Warn("Failed to parse config file from repository, using default config", "error", err)
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	if err := v.ReadConfig(strings.NewReader(string(decoded))); err != nil {
+		return nil, &InvalidConfigError{Layer: name, Err: fmt.Errorf("failed to parse config: %w", err)}
 	}
 
-	var repoConfig Config
-	err = v.Unmarshal(&repoConfig)
-	if err != nil {
-		cl.logger.Warn("Failed to unmarshal config file from repository, using default config", "error", err)
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	values, _ := v.Get("rules").(map[string]interface{})
+	if values == nil {
+		values = make(map[string]interface{})
 	}
 
-	cl.logger.Debug("Successfully loaded config from repository")
-	return &repoConfig.Rules, nil
+	return values, nil
 }
 
-// selectConfig returns repository config if available, otherwise default config
-func (cl *ConfigLoader) selectConfig(repoConfig *RulesConfig) RulesConfig {
-	if repoConfig != nil {
-		cl.logger.Debug("Using repository configuration")
-		return *repoConfig
+// parentGroups returns every group of a namespace path, top-level group first
+func parentGroups(fullPath string) []string {
+	var groups []string
+	parts := strings.Split(strings.Trim(fullPath, "/"), "/")
+	for i := range parts {
+		if parts[i] == "" {
+			continue
+		}
+		groups = append(groups, strings.Join(parts[:i+1], "/"))
 	}
-
-	cl.logger.Info("Using default configuration")
-	return cl.defaultConfig
+	return groups
 }
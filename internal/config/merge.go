@@ -0,0 +1,164 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// Layer is a single source of rules configuration, merged in order of precedence
+type Layer struct {
+	Name   string
+	Values map[string]interface{}
+}
+
+// EffectiveConfig is the result of merging all configuration layers for a project
+type EffectiveConfig struct {
+	Rules RulesConfig
+	// Values is the merged raw configuration, keyed as in .mr-conform.yaml
+	Values map[string]interface{}
+	// Layers lists the names of the layers that were merged, lowest precedence first
+	Layers []string
+	// Sources maps each dotted configuration key to the layer that set it
+	Sources map[string]string
+	// Warnings describes configuration files that were ignored because they are invalid
+	Warnings []string
+}
+
+// MergeLayers deep-merges layers in order, later layers taking precedence.
+// Mappings are merged key by key, while scalars and lists replace inherited
+// values. An explicit empty list resets an inherited list; keys without a
+// value (null) keep the inherited value.
+func MergeLayers(layers []Layer) (map[string]interface{}, map[string]string) {
+	merged := make(map[string]interface{})
+	sources := make(map[string]string)
+
+	for _, layer := range layers {
+		mergeInto(merged, layer.Values, layer.Name, "", sources)
+	}
+
+	return merged, sources
+}
+
+func mergeInto(dst, src map[string]interface{}, layer, prefix string, sources map[string]string) {
+	for key, value := range src {
+		if value == nil {
+			continue
+		}
+
+		path := joinKey(prefix, key)
+
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+
+		switch {
+		case srcIsMap && dstIsMap:
+			mergeInto(dstMap, srcMap, layer, path, sources)
+		case srcIsMap:
+			dstMap = make(map[string]interface{})
+			dropSources(sources, path)
+			mergeInto(dstMap, srcMap, layer, path, sources)
+			dst[key] = dstMap
+		default:
+			dropSources(sources, path)
+			dst[key] = value
+			sources[path] = layer
+		}
+	}
+}
+
+// dropSources forgets the origin of a key and everything nested below it
+func dropSources(sources map[string]string, path string) {
+	for key := range sources {
+		if key == path || strings.HasPrefix(key, path+".") {
+			delete(sources, key)
+		}
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// SortedSourceKeys returns the keys of sources in lexical order
+func SortedSourceKeys(sources map[string]string) []string {
+	keys := make([]string, 0, len(sources))
+	for key := range sources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ToMap converts a configuration struct into a raw map keyed by its
+// mapstructure tags, so it can be merged with configuration read from files
+func ToMap(v interface{}) map[string]interface{} {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	out := make(map[string]interface{})
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("mapstructure")
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldValue := value.Field(i)
+
+		// Remaining keys are flattened back into the parent mapping
+		if strings.Contains(opts, "remain") {
+			if extra, ok := fieldValue.Interface().(map[string]interface{}); ok {
+				for key, raw := range extra {
+					out[key] = raw
+				}
+			}
+			continue
+		}
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if fieldValue.Kind() == reflect.Struct {
+			out[name] = ToMap(fieldValue.Interface())
+			continue
+		}
+		out[name] = fieldValue.Interface()
+	}
+
+	return out
+}
+
+// Decode decodes raw configuration into target using the same weak typing
+// viper applies when unmarshalling the main configuration
+func Decode(raw interface{}, target interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           target,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(raw)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeLayers_DeepMerge(t *testing.T) {
+	defaults := RulesConfig{
+		Title: TitleConfig{
+			Enabled:        true,
+			MinLength:      10,
+			MaxLength:      100,
+			ForbiddenWords: []string{"WIP", "TODO"},
+		},
+		Branch: BranchConfig{
+			Enabled:         true,
+			AllowedPrefixes: []string{"feature/", "bugfix/"},
+		},
+	}
+
+	layers := []Layer{
+		{Name: "default", Values: ToMap(defaults)},
+		{Name: "group:org", Values: map[string]interface{}{
+			"title": map[string]interface{}{"min_length": 15},
+		}},
+		{Name: "repository", Values: map[string]interface{}{
+			"title": map[string]interface{}{"max_length": 72},
+		}},
+	}
+
+	merged, sources := MergeLayers(layers)
+
+	var rules RulesConfig
+	if err := Decode(merged, &rules); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	if rules.Title.MinLength != 15 || rules.Title.MaxLength != 72 {
+		t.Errorf("expected min=15 max=72, got min=%d max=%d", rules.Title.MinLength, rules.Title.MaxLength)
+	}
+	if !rules.Title.Enabled || !rules.Branch.Enabled {
+		t.Error("expected untouched default values to be inherited")
+	}
+	if !reflect.DeepEqual(rules.Title.ForbiddenWords, []string{"WIP", "TODO"}) {
+		t.Errorf("expected inherited forbidden words, got %v", rules.Title.ForbiddenWords)
+	}
+
+	expectedSources := map[string]string{
+		"title.min_length": "group:org",
+		"title.max_length": "repository",
+		"title.enabled":    "default",
+	}
+	for key, want := range expectedSources {
+		if got := sources[key]; got != want {
+			t.Errorf("%s: expected source %q, got %q", key, want, got)
+		}
+	}
+}
+
+func TestMergeLayers_Lists(t *testing.T) {
+	layers := []Layer{
+		{Name: "default", Values: map[string]interface{}{
+			"branch": map[string]interface{}{
+				"allowed_prefixes": []interface{}{"feature/", "bugfix/"},
+				"forbidden_names":  []interface{}{"main"},
+			},
+			"title": map[string]interface{}{
+				"forbidden_words": []interface{}{"WIP"},
+			},
+		}},
+		{Name: "repository", Values: map[string]interface{}{
+			"branch": map[string]interface{}{
+				"allowed_prefixes": []interface{}{"feat/"},
+				"forbidden_names":  []interface{}{},
+			},
+			"title": map[string]interface{}{
+				"forbidden_words": nil,
+			},
+		}},
+	}
+
+	merged, sources := MergeLayers(layers)
+
+	var rules RulesConfig
+	if err := Decode(merged, &rules); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	if !reflect.DeepEqual(rules.Branch.AllowedPrefixes, []string{"feat/"}) {
+		t.Errorf("expected list to be replaced, got %v", rules.Branch.AllowedPrefixes)
+	}
+	if len(rules.Branch.ForbiddenNames) != 0 {
+		t.Errorf("expected empty list to reset inherited list, got %v", rules.Branch.ForbiddenNames)
+	}
+	if !reflect.DeepEqual(rules.Title.ForbiddenWords, []string{"WIP"}) {
+		t.Errorf("expected null to keep inherited list, got %v", rules.Title.ForbiddenWords)
+	}
+	if sources["title.forbidden_words"] != "default" {
+		t.Errorf("expected forbidden_words from default, got %q", sources["title.forbidden_words"])
+	}
+}
+
+func TestSortedSourceKeys(t *testing.T) {
+	keys := SortedSourceKeys(map[string]string{"title.max_length": "group", "branch.enabled": "default", "title.enabled": "project"})
+	if want := []string{"branch.enabled", "title.enabled", "title.max_length"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+}
+
+func TestToMap_FlattensExtra(t *testing.T) {
+	values := ToMap(RulesConfig{
+		Extra: map[string]interface{}{
+			"labels": map[string]interface{}{"enabled": true},
+		},
+	})
+
+	if _, ok := values["labels"]; !ok {
+		t.Error("expected extra rules to be flattened into the rules mapping")
+	}
+	if _, ok := values["title"].(map[string]interface{}); !ok {
+		t.Error("expected nested config structs to be converted to maps")
+	}
+}
+
+func TestParentGroups(t *testing.T) {
+	got := parentGroups("org/platform/backend")
+	want := []string{"org", "org/platform", "org/platform/backend"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// SchemaID is the published location of the .mr-conform.yaml JSON Schema
+const SchemaID = "https://raw.githubusercontent.com/chrxmvtik/gitlab-mr-conform/main/schemas/mr-conform.schema.json"
+
+// RulesSchema generates the JSON Schema of a .mr-conform.yaml file from
+// RulesConfig. Extra maps the keys of additionally registered rules to their
+// configuration type.
+func RulesSchema(extra map[string]reflect.Type) map[string]interface{} {
+	rules := schemaFor(reflect.TypeOf(RulesConfig{}), "")
+	properties := rules["properties"].(map[string]interface{})
+	for key, t := range extra {
+		if _, ok := properties[key]; !ok {
+			properties[key] = schemaFor(t, "")
+		}
+	}
+
+	// Sections of the bot configuration are accepted but ignored in repository files
+	root := map[string]interface{}{"rules": rules}
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		name, _, _ := strings.Cut(configType.Field(i).Tag.Get("mapstructure"), ",")
+		if _, ok := root[name]; !ok && name != "" {
+			root[name] = map[string]interface{}{"description": "Ignored in repository configuration"}
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"$id":                  SchemaID,
+		"title":                "GitLab MR Conform repository configuration",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           root,
+	}
+}
+
+func schemaFor(t reflect.Type, check string) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.String() == "time.Duration" {
+		return map[string]interface{}{"type": "string", "pattern": `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			properties[name] = schemaFor(field.Type, field.Tag.Get("validate"))
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": false,
+			"properties":           properties,
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem(), check),
+		}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		schema := map[string]interface{}{"type": "string"}
+		switch check {
+		case "regexp":
+			schema["format"] = "regex"
+		case "severity":
+			schema["enum"] = slices.Concat(SeverityNames, severityAliases)
+		}
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
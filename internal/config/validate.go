@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	doublestar "github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/viper"
+)
+
+// SeverityNames lists the values accepted by the severity option of a rule
+var SeverityNames = []string{"error", "warning", "info"}
+
+// severityAliases are accepted for the severity option besides SeverityNames
+var severityAliases = []string{"warn"}
+
+// ValidationIssue describes a single problem found in a configuration file
+type ValidationIssue struct {
+	Path    string
+	Message string
+}
+
+func (i ValidationIssue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// ValidateFile validates the content of a .mr-conform.yaml file. Extra maps the
+// keys of additionally registered rules to their configuration type.
+func ValidateFile(data []byte, extra map[string]reflect.Type) ([]ValidationIssue, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	if err := v.ReadConfig(strings.NewReader(string(data))); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Sections of the bot configuration are accepted but ignored in repository files
+	var issues []ValidationIssue
+	for _, key := range sortedKeys(v.AllSettings()) {
+		if _, ok := fieldByTag(reflect.TypeOf(Config{}), key); !ok {
+			issues = append(issues, ValidationIssue{Path: key, Message: "unknown key"})
+		}
+	}
+
+	raw := v.Get("rules")
+	if raw == nil {
+		return issues, nil
+	}
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return append(issues, ValidationIssue{Path: "rules", Message: fmt.Sprintf("expected a mapping, got %s", describe(raw))}), nil
+	}
+
+	return append(issues, ValidateRules(values, extra)...), nil
+}
+
+// ValidateRules validates the rules section of a configuration file, reporting
+// unknown keys, values of the wrong type, invalid regular expressions and
+// invalid glob patterns
+func ValidateRules(values map[string]interface{}, extra map[string]reflect.Type) []ValidationIssue {
+	var issues []ValidationIssue
+	rulesType := reflect.TypeOf(RulesConfig{})
+
+	for _, key := range sortedKeys(values) {
+		path := joinKey("rules", key)
+
+		if field, ok := fieldByTag(rulesType, key); ok {
+			validateValue(path, values[key], field.Type, field.Tag.Get("validate"), &issues)
+			continue
+		}
+
+		if t, ok := extra[key]; ok {
+			validateValue(path, values[key], t, "", &issues)
+			continue
+		}
+
+		issues = append(issues, ValidationIssue{Path: path, Message: "unknown rule"})
+	}
+
+	return issues
+}
+
+func validateValue(path string, value interface{}, t reflect.Type, check string, issues *[]ValidationIssue) {
+	if value == nil {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			*issues = append(*issues, ValidationIssue{Path: path, Message: fmt.Sprintf("expected a mapping, got %s", describe(value))})
+			return
+		}
+		for _, key := range sortedKeys(values) {
+			field, ok := fieldByTag(t, key)
+			if !ok {
+				*issues = append(*issues, ValidationIssue{Path: joinKey(path, key), Message: "unknown key"})
+				continue
+			}
+			validateValue(joinKey(path, key), values[key], field.Type, field.Tag.Get("validate"), issues)
+		}
+
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			*issues = append(*issues, ValidationIssue{Path: path, Message: fmt.Sprintf("expected a list, got %s", describe(value))})
+			return
+		}
+		for i, item := range items {
+			validateValue(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), check, issues)
+		}
+
+	case reflect.Map, reflect.Interface:
+		// Free-form values are accepted as-is
+
+	default:
+		target := reflect.New(t)
+		if err := Decode(value, target.Interface()); err != nil {
+			*issues = append(*issues, ValidationIssue{Path: path, Message: fmt.Sprintf("expected %s, got %s", typeName(t), describe(value))})
+			return
+		}
+		if msg := checkValue(check, target.Elem().Interface()); msg != "" {
+			*issues = append(*issues, ValidationIssue{Path: path, Message: msg})
+		}
+	}
+}
+
+// checkValue applies the check named in a `validate` struct tag
+func checkValue(check string, value interface{}) string {
+	s, _ := value.(string)
+
+	switch check {
+	case "regexp":
+		if _, err := regexp.Compile(s); err != nil {
+			return fmt.Sprintf("invalid regular expression %q: %v", s, err)
+		}
+	case "glob":
+		if !doublestar.ValidatePattern(s) {
+			return fmt.Sprintf("invalid glob pattern %q", s)
+		}
+	case "severity":
+		for _, name := range slices.Concat(SeverityNames, severityAliases) {
+			if strings.EqualFold(s, name) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("invalid severity %q: must be one of %s", s, strings.Join(SeverityNames, ", "))
+	}
+
+	return ""
+}
+
+// fieldByTag finds the struct field decoded from the given key
+func fieldByTag(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name != "" && strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t.String() == "time.Duration" {
+			return "a duration"
+		}
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	default:
+		return t.String()
+	}
+}
+
+func describe(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "a mapping"
+	case []interface{}:
+		return "a list"
+	case string:
+		return fmt.Sprintf("%q", value)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
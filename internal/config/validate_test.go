@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	data := []byte(`
+rules:
+  title:
+    enabled: true
+    max_length: "long"
+    severity: fatal
+    conventional:
+      scopes: ["(api", "core"]
+  squash:
+    enforce_branches: ["feature/[", "fix/*"]
+  branch:
+    allowed_prefix: ["feature/"]
+  titel:
+    enabled: true
+  labels:
+    enabled: true
+    min_count: "two"
+`)
+
+	type labelsConfig struct {
+		Enabled  bool `mapstructure:"enabled"`
+		MinCount int  `mapstructure:"min_count"`
+	}
+
+	issues, err := ValidateFile(data, map[string]reflect.Type{"labels": reflect.TypeOf(labelsConfig{})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"rules.branch.allowed_prefix: unknown key",
+		"rules.labels.min_count: expected an integer",
+		"rules.squash.enforce_branches[0]: invalid glob pattern",
+		"rules.title.conventional.scopes[0]: invalid regular expression",
+		"rules.title.max_length: expected an integer",
+		"rules.title.severity: invalid severity",
+		"rules.titel: unknown rule",
+	}
+
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %d: %v", len(expected), len(issues), issues)
+	}
+	for _, want := range expected {
+		found := false
+		for _, issue := range issues {
+			if strings.HasPrefix(issue.String(), want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected issue %q, got %v", want, issues)
+		}
+	}
+}
+
+func TestValidateFile_Valid(t *testing.T) {
+	data := []byte(`
+server:
+  port: 8080
+rules:
+  title:
+    enabled: true
+    severity: warning
+    max_length: 72
+    conventional:
+      types: ["feat", "fix"]
+      scopes: ["^api$", ".*"]
+  squash:
+    severity: warn
+    enforce_branches: ["feature/**"]
+`)
+
+	issues, err := ValidateFile(data, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestValidateFile_InvalidYAML(t *testing.T) {
+	if _, err := ValidateFile([]byte("rules: [unclosed"), nil); err == nil {
+		t.Error("expected parse error for invalid YAML")
+	}
+}
+
+func TestRulesSchema_UpToDate(t *testing.T) {
+	published, err := os.ReadFile("../../schemas/mr-conform.schema.json")
+	if err != nil {
+		t.Fatalf("failed to read published schema: %v", err)
+	}
+
+	var generated bytes.Buffer
+	encoder := json.NewEncoder(&generated)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(RulesSchema(nil)); err != nil {
+		t.Fatalf("failed to encode schema: %v", err)
+	}
+
+	if !bytes.Equal(published, generated.Bytes()) {
+		t.Error("schemas/mr-conform.schema.json is out of date, run `make schema`")
+	}
+}
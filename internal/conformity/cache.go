@@ -0,0 +1,155 @@
+package conformity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/internal/conformity/helper/common"
+	"gitlab-mr-conformity-bot/internal/conformity/rules"
+	"gitlab-mr-conformity-bot/internal/storage"
+
+	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+)
+
+// resultCache stores the latest check result of each merge request, together
+// with a fingerprint per rule so unchanged rules can be skipped on the next check
+type resultCache struct {
+	store storage.Storage
+	ttl   time.Duration
+}
+
+// cacheEntry is the cached check of a merge request
+type cacheEntry struct {
+	// Key identifies the inputs of the whole result: head SHA, configuration,
+	// approval state and merge request details. It is empty when the result
+	// is incomplete and must not be reused as a whole.
+	Key string `json:"key"`
+	// Rules holds the fingerprint and outcome of every rule, by rule name
+	Rules    map[string]ruleEntry `json:"rules"`
+	Result   *CheckResult         `json:"result"`
+	StoredAt time.Time            `json:"stored_at"`
+}
+
+// ruleEntry is the cached outcome of a single rule
+type ruleEntry struct {
+	Fingerprint string       `json:"fingerprint"`
+	Failure     *RuleFailure `json:"failure,omitempty"`
+}
+
+func newResultCache(store storage.Storage, ttl time.Duration) *resultCache {
+	return &resultCache{store: store, ttl: ttl}
+}
+
+func resultCacheKey(projectID, mrIID int) string {
+	return fmt.Sprintf("check:%d:%d", projectID, mrIID)
+}
+
+// get returns the cached check of a merge request, or nil when there is none or it expired
+func (rc *resultCache) get(projectID, mrIID int) (*cacheEntry, error) {
+	value, err := rc.store.Get(resultCacheKey(projectID, mrIID))
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected cached value of type %T", value)
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cached result: %w", err)
+	}
+	if entry.Result == nil || (rc.ttl > 0 && time.Since(entry.StoredAt) > rc.ttl) {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+// put stores the check of a merge request, replacing the previous one
+func (rc *resultCache) put(projectID, mrIID int, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	return rc.store.SetWithTTL(resultCacheKey(projectID, mrIID), data, rc.ttl)
+}
+
+// reusable returns the cached outcome of a rule when its fingerprint is unchanged
+func (e *cacheEntry) reusable(name, fingerprint string) (ruleEntry, bool) {
+	if e == nil || fingerprint == "" {
+		return ruleEntry{}, false
+	}
+	entry, ok := e.Rules[name]
+	return entry, ok && entry.Fingerprint == fingerprint
+}
+
+// resultKey identifies everything a complete check result depends on
+func resultKey(mr *gitlabapi.MergeRequest, configVersion string, approvals *common.Approvals) string {
+	return hashJSON(
+		mr.SHA,
+		configVersion,
+		hashJSON(approvals),
+		inputValues(rules.InputAll, mr, approvals, mr.TargetBranch),
+	)
+}
+
+// configVersion identifies a rules configuration
+func configVersion(rulesConfig config.RulesConfig) string {
+	return hashJSON(rulesConfig)
+}
+
+// ruleFingerprint identifies a rule, its configuration and the data it reads
+func ruleFingerprint(cr configuredRule, mr *gitlabapi.MergeRequest, approvals *common.Approvals, ref string) string {
+	return hashJSON(
+		cr.rule.Name(),
+		cr.rule.Severity(),
+		cr.config,
+		inputValues(rules.InputsOf(cr.rule), mr, approvals, ref),
+	)
+}
+
+// inputValues collects the merge request data selected by inputs. Commits and
+// changes are identified by the head SHA; CODEOWNERS also by the ref it is read at.
+func inputValues(inputs rules.Input, mr *gitlabapi.MergeRequest, approvals *common.Approvals, ref string) map[string]interface{} {
+	values := make(map[string]interface{})
+	if inputs&rules.InputTitle != 0 {
+		values["title"] = mr.Title
+	}
+	if inputs&rules.InputDescription != 0 {
+		values["description"] = mr.Description
+	}
+	if inputs&rules.InputBranches != 0 {
+		values["branches"] = []string{mr.SourceBranch, mr.TargetBranch}
+	}
+	if inputs&rules.InputCommits != 0 {
+		values["commits"] = mr.SHA
+	}
+	if inputs&rules.InputApprovals != 0 {
+		values["approvals"] = hashJSON(approvals)
+	}
+	if inputs&rules.InputChanges != 0 {
+		values["changes"] = []string{mr.SHA, ref}
+	}
+	if inputs&rules.InputSettings != 0 {
+		values["settings"] = []bool{mr.Squash, mr.SquashOnMerge}
+	}
+	return values
+}
+
+// hashJSON returns a SHA-256 digest of the JSON encoding of values. Map keys are
+// encoded in sorted order, so equal values always have the same digest.
+func hashJSON(values ...interface{}) string {
+	data, err := json.Marshal(values)
+	if err != nil {
+		// Values that cannot be encoded never match a cached entry
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
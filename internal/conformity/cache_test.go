@@ -0,0 +1,86 @@
+package conformity
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/internal/conformity/rules"
+	"gitlab-mr-conformity-bot/internal/storage"
+
+	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestRuleFingerprint_OnlyAffectedRulesChange(t *testing.T) {
+	rb := NewRuleBuilder(config.IntegrationsConfig{})
+	configured, err := rb.buildConfiguredRules(config.RulesConfig{
+		Title:  config.TitleConfig{Enabled: true, MaxLength: 50},
+		Branch: config.BranchConfig{Enabled: true},
+		Extra:  map[string]interface{}{"test_labels": map[string]interface{}{"enabled": true}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	before := &gitlabapi.MergeRequest{BasicMergeRequest: gitlabapi.BasicMergeRequest{Title: "feat: a", SourceBranch: "feature/a", TargetBranch: "main", SHA: "abc"}}
+	after := *before
+	after.Title = "feat: b"
+
+	changed := make(map[string]bool)
+	for _, cr := range configured {
+		changed[cr.rule.Name()] = ruleFingerprint(cr, before, nil, "main") != ruleFingerprint(cr, &after, nil, "main")
+	}
+
+	if len(changed) != 3 {
+		t.Fatalf("expected 3 rules, got %v", changed)
+	}
+	for name, differs := range changed {
+		// The label rule does not declare its inputs and depends on everything
+		want := name != "Branch Naming"
+		if differs != want {
+			t.Errorf("rule %q: fingerprint changed = %v, want %v", name, differs, want)
+		}
+	}
+}
+
+func TestResultCache_RoundTripAndExpiry(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	cache := newResultCache(store, time.Minute)
+
+	failure := &RuleFailure{RuleName: "Title Validation", Severity: rules.SeverityError, Error: []string{"too long"}}
+	entry := &cacheEntry{
+		Key:      "key",
+		Rules:    map[string]ruleEntry{"Title Validation": {Fingerprint: "fp", Failure: failure}},
+		Result:   &CheckResult{Failures: []RuleFailure{*failure}, Summary: "summary"},
+		StoredAt: time.Now(),
+	}
+	if err := cache.put(1, 2, entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := cache.get(1, 2)
+	if err != nil || got == nil {
+		t.Fatalf("expected cached entry, got %v (%v)", got, err)
+	}
+	if got.Key != "key" || got.Result.Summary != "summary" || got.Result.Failures[0].Severity != rules.SeverityError {
+		t.Errorf("unexpected entry: %+v", got)
+	}
+	if reused, ok := got.reusable("Title Validation", "fp"); !ok || reused.Failure == nil || reused.Failure.Error[0] != "too long" {
+		t.Errorf("expected rule outcome to be reusable, got %+v", reused)
+	}
+	if _, ok := got.reusable("Title Validation", "other"); ok {
+		t.Error("expected changed fingerprint not to be reusable")
+	}
+
+	if other, _ := cache.get(1, 3); other != nil {
+		t.Errorf("expected no entry for another merge request, got %+v", other)
+	}
+
+	entry.StoredAt = time.Now().Add(-2 * time.Minute)
+	if err := cache.put(1, 2, entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expired, _ := cache.get(1, 2); expired != nil {
+		t.Errorf("expected expired entry to be ignored, got %+v", expired)
+	}
+}
@@ -1,20 +1,27 @@
 package conformity
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
+	"time"
 
 	"gitlab-mr-conformity-bot/internal/config"
 	"gitlab-mr-conformity-bot/internal/conformity/helper/codeowners"
 	"gitlab-mr-conformity-bot/internal/conformity/helper/common"
 	"gitlab-mr-conformity-bot/internal/conformity/rules"
 	"gitlab-mr-conformity-bot/internal/gitlab"
+	"gitlab-mr-conformity-bot/internal/history"
+	"gitlab-mr-conformity-bot/internal/metrics"
+	"gitlab-mr-conformity-bot/internal/storage"
+	"gitlab-mr-conformity-bot/internal/tracing"
 	"gitlab-mr-conformity-bot/pkg/logger"
 
 	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Checker struct {
@@ -22,13 +29,26 @@ type Checker struct {
 	ruleBuilder      *RuleBuilder
 	summaryGenerator *SummaryGenerator
 	gitlabClient     *gitlab.Client
+	groupResolver    *groupResolver
+	cache            *resultCache
+	history          *history.Store
+	checkTimeout     time.Duration
+	preview          config.PreviewConfig
 	logger           *logger.Logger
 }
 
+// CheckResult is the outcome of a conformity check. Passed is false only when
+// a blocking (error-level) failure was found; warnings and info findings are
+// reported in Failures but do not fail the merge request.
 type CheckResult struct {
-	Passed   bool
-	Failures []RuleFailure
-	Summary  string
+	Passed         bool
+	Failures       []RuleFailure
+	ConfigWarnings []string
+	Codeowners     *CodeownersSource
+	Preview        *ConfigPreview
+	Summary        string
+	// Cached is true when the result was served from the cache without running any rule
+	Cached bool
 }
 
 type RuleFailure struct {
@@ -38,125 +58,450 @@ type RuleFailure struct {
 	Suggestion []string
 }
 
-func NewChecker(defaultConfig config.RulesConfig, client *gitlab.Client, log *logger.Logger, integrations config.IntegrationsConfig) *Checker {
+// CodeownersSource identifies the CODEOWNERS file used for a check
+type CodeownersSource struct {
+	Path string
+	Ref  string
+}
+
+// CheckOptions controls how a check uses cached results
+type CheckOptions struct {
+	// Refresh runs every rule, ignoring cached results
+	Refresh bool
+}
+
+// evaluation holds the outcome of running one set of rules against a merge request
+type evaluation struct {
+	ruleNames  []string
+	failures   []RuleFailure
+	codeowners *CodeownersSource
+	// rules holds the fingerprint and outcome of every rule that completed
+	rules map[string]ruleEntry
+	// ruleErrors holds the rules that could not be checked
+	ruleErrors map[string]error
+	// reused holds the rules whose cached outcome was reused
+	reused map[string]bool
+}
+
+func NewChecker(cfg *config.Config, client *gitlab.Client, store storage.Storage, log *logger.Logger) *Checker {
+	configLoader := config.NewConfigLoader(cfg.Rules, cfg.Inheritance, client, log)
+	configLoader.SetRuleTypes(rules.ConfigTypes())
+
+	var cache *resultCache
+	if cfg.Cache.Enabled && store != nil {
+		cache = newResultCache(store, cfg.Cache.TTL)
+	}
+
+	var historyStore *history.Store
+	if cfg.History.Enabled && store != nil {
+		historyStore = history.NewStore(store, cfg.History.MaxEntries)
+	}
+
 	return &Checker{
-		configLoader:     config.NewConfigLoader(defaultConfig, client, log),
-		ruleBuilder:      NewRuleBuilder(integrations),
+		configLoader:     configLoader,
+		ruleBuilder:      NewRuleBuilder(cfg.Integrations),
 		summaryGenerator: NewSummaryGenerator(),
 		gitlabClient:     client,
+		groupResolver:    newGroupResolver(client, groupMembersTTL, log),
+		cache:            cache,
+		history:          historyStore,
+		checkTimeout:     cfg.Server.CheckTimeout,
+		preview:          cfg.Preview,
 		logger:           log,
 	}
 }
 
-func (c *Checker) CheckMergeRequest(projectID interface{}, mrID int) (*CheckResult, error) {
-	// Load configuration (repository or default)
-	finalConfig, err := c.configLoader.LoadConfig(projectID)
+// History returns the store check runs are recorded in, nil when history is disabled
+func (c *Checker) History() *history.Store {
+	return c.history
+}
+
+// EffectiveConfig returns the merged configuration of a project and the layer each value came from
+func (c *Checker) EffectiveConfig(ctx context.Context, projectID interface{}) (*config.EffectiveConfig, error) {
+	return c.configLoader.LoadEffectiveConfig(ctx, projectID)
+}
+
+// CheckMergeRequest checks a merge request, reusing cached results where its inputs are unchanged
+func (c *Checker) CheckMergeRequest(ctx context.Context, projectID interface{}, mrID int) (*CheckResult, error) {
+	return c.CheckMergeRequestWithOptions(ctx, projectID, mrID, CheckOptions{})
+}
+
+// CheckMergeRequestWithOptions checks a merge request and records check metrics
+func (c *Checker) CheckMergeRequestWithOptions(ctx context.Context, projectID interface{}, mrID int, opts CheckOptions) (*CheckResult, error) {
+	startedAt := time.Now()
+
+	ctx, span := tracing.Start(ctx, "conformity.check", trace.WithAttributes(
+		attribute.String("gitlab.project_id", fmt.Sprint(projectID)),
+		attribute.Int("gitlab.merge_request_iid", mrID),
+		attribute.Bool("check.refresh", opts.Refresh),
+	))
+	result, err := c.checkMergeRequest(ctx, projectID, mrID, opts, startedAt)
+	if result != nil {
+		span.SetAttributes(attribute.Bool("check.cached", result.Cached), attribute.Bool("check.passed", result.Passed))
+	}
+	tracing.End(span, err)
+
+	switch {
+	case err != nil:
+		metrics.ObserveCheck("error", startedAt)
+	case result.Cached:
+		metrics.ObserveCheck("cached", startedAt)
+	case result.Passed:
+		metrics.ObserveCheck("passed", startedAt)
+	default:
+		metrics.ObserveCheck("failed", startedAt)
+	}
+	return result, err
+}
+
+func (c *Checker) checkMergeRequest(ctx context.Context, projectID interface{}, mrID int, opts CheckOptions, startedAt time.Time) (*CheckResult, error) {
+	if c.checkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.checkTimeout)
+		defer cancel()
+	}
+
+	// Load configuration (defaults, groups and repository merged)
+	effective, err := c.configLoader.LoadEffectiveConfig(ctx, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
+	finalConfig := effective.Rules
 
-	// Build rules based on configuration
-	rulesList := c.ruleBuilder.BuildRules(finalConfig)
-
-	// Get merge request and commits
-	mr, commits, approvals, err := c.fetchMergeRequestData(projectID, mrID, finalConfig)
+	// Get merge request and approvals, commits and changes are fetched when a rule needs them
+	data, err := c.fetchMergeRequestData(ctx, projectID, mrID, finalConfig)
 	if err != nil {
 		return nil, err
 	}
+	mr := data.mr
+
+	// Serve the previous result when nothing it depends on has changed
+	version := configVersion(finalConfig)
+	key := resultKey(mr, version, data.approvals)
+	var previous *cacheEntry
+	if c.cache != nil && !opts.Refresh {
+		previous, err = c.cache.get(mr.ProjectID, mr.IID)
+		if err != nil {
+			c.logger.Warn("Failed to read cached result", "projectId", mr.ProjectID, "mrId", mr.IID, "error", err)
+		}
+		if previous != nil && key != "" && previous.Key == key {
+			c.logger.Debug("Using cached result", "projectId", mr.ProjectID, "mrId", mr.IID, "sha", mr.SHA)
+			result := *previous.Result
+			result.Cached = true
+			return &result, nil
+		}
+	}
 
-	var co []*codeowners.PatternGroup
+	// CODEOWNERS is read from the target branch, as GitLab does
+	current, err := c.evaluate(ctx, data, data.approvals, finalConfig, mr.TargetBranch, previous)
+	if err != nil {
+		return nil, err
+	}
 
-	var members []*gitlabapi.ProjectMember
+	// Results of a cancelled check are incomplete and must not be reported
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("check cancelled: %w", err)
+	}
+	observeRuleResults(current)
 
-	if finalConfig.Approvals.UseCodeowners {
-		// Get project members
-		members, err = c.gitlabClient.ListProjectMembers(projectID)
+	// Preview the proposed configuration without enforcing it
+	var preview *ConfigPreview
+	complete := len(current.ruleErrors) == 0
+	if c.preview.Enabled {
+		paths, err := data.getPaths(ctx)
 		if err != nil {
-			c.logger.Info("Failed to list project members", "error", err)
+			return nil, err
 		}
-		// Get CODEOWNERS file from repository
-		co, err = c.getCodeowners(projectID, mrID, members)
+		if changed := changedConfigFiles(paths); len(changed) > 0 {
+			preview, err = c.previewConfig(ctx, data, finalConfig, changed, current)
+			if err != nil {
+				c.logger.Warn("Failed to preview proposed configuration", "projectId", projectID, "mrId", mrID, "error", err)
+				complete = false
+			}
+		}
+	}
+
+	// Generate results, only error-level failures are blocking
+	passed := countBlocking(current.failures) == 0
+	summary := c.summaryGenerator.GenerateSummary(current.failures, effective.Warnings)
+	if current.codeowners != nil {
+		summary += c.summaryGenerator.FormatCodeownersSource(current.codeowners)
+	}
+	if preview != nil {
+		summary += c.summaryGenerator.FormatPreview(preview)
+	}
+
+	result := &CheckResult{
+		Passed:         passed,
+		Failures:       current.failures,
+		ConfigWarnings: effective.Warnings,
+		Codeowners:     current.codeowners,
+		Preview:        preview,
+		Summary:        summary,
+	}
+
+	if c.cache != nil {
+		c.logger.Debug("Checked merge request", "projectId", mr.ProjectID, "mrId", mr.IID, "rules", len(current.ruleNames), "reused", len(current.reused))
+
+		// Incomplete results are only reused rule by rule
+		if !complete {
+			key = ""
+		}
+		entry := &cacheEntry{Key: key, Rules: current.rules, Result: result, StoredAt: time.Now()}
+		if err := c.cache.put(mr.ProjectID, mr.IID, entry); err != nil {
+			c.logger.Warn("Failed to cache result", "projectId", mr.ProjectID, "mrId", mr.IID, "error", err)
+		}
+	}
+
+	// Cached results repeat a recorded verdict, only evaluations are recorded
+	if c.history != nil {
+		record := newHistoryRecord(mr, version, data.approvals, current, result, startedAt)
+		if err := c.history.Add(record); err != nil {
+			c.logger.Warn("Failed to record check history", "projectId", mr.ProjectID, "mrId", mr.IID, "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+// evaluate builds the rules of rulesConfig and runs them against the merge request.
+// CODEOWNERS is read at ref, or from the default branch when ref is empty. Rules
+// whose fingerprint matches the previous check reuse its outcome, and data only
+// needed by those rules is not fetched.
+func (c *Checker) evaluate(ctx context.Context, data *mergeRequestData, approvals *common.Approvals, rulesConfig config.RulesConfig, ref string, previous *cacheEntry) (*evaluation, error) {
+	// Build rules based on configuration
+	configured, err := c.ruleBuilder.buildConfiguredRules(rulesConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules configuration: %w", err)
+	}
+
+	eval := &evaluation{
+		ruleNames:  make([]string, 0, len(configured)),
+		rules:      make(map[string]ruleEntry, len(configured)),
+		ruleErrors: make(map[string]error),
+		reused:     make(map[string]bool),
+	}
+
+	// Find the rules that have to run and the data they read
+	fingerprints := make([]string, len(configured))
+	var needed rules.Input
+	for i, cr := range configured {
+		eval.ruleNames = append(eval.ruleNames, cr.rule.Name())
+		fingerprints[i] = ruleFingerprint(cr, data.mr, approvals, ref)
+		if _, ok := previous.reusable(cr.rule.Name(), fingerprints[i]); !ok {
+			needed |= rules.InputsOf(cr.rule)
+		}
+	}
+
+	var commits []*gitlabapi.Commit
+	if needed&rules.InputCommits != 0 {
+		commits, err = data.getCommits(ctx)
 		if err != nil {
-			c.logger.Info("No CODEOWNERS file found in repository, skipping", "error", err)
+			return nil, err
 		}
+	}
+
+	var co []*codeowners.PatternGroup
+	var members []*gitlabapi.ProjectMember
 
+	if rulesConfig.Approvals.UseCodeowners {
+		if needed&rules.InputChanges != 0 {
+			co, members, eval.codeowners, err = c.loadCodeowners(ctx, data, ref)
+			if err != nil {
+				return nil, err
+			}
+		} else if previous != nil {
+			// Rules reading CODEOWNERS are reused, so is the file they used
+			eval.codeowners = previous.Result.Codeowners
+		}
 	}
 
-	// Execute rule checks
-	failures := c.executeRuleChecks(rulesList, mr, commits, approvals, co, members)
+	// Execute rule checks, in rule order
+	for i, cr := range configured {
+		name := cr.rule.Name()
+		if entry, ok := previous.reusable(name, fingerprints[i]); ok {
+			c.logger.Debug("Reusing cached rule result", "rule", name)
+			eval.rules[name] = entry
+			eval.reused[name] = true
+			if entry.Failure != nil {
+				eval.failures = append(eval.failures, *entry.Failure)
+			}
+			continue
+		}
 
-	// Generate results
-	passed := len(failures) == 0
-	summary := c.summaryGenerator.GenerateSummary(failures)
+		failure, err := c.executeRuleCheck(ctx, cr.rule, data.mr, commits, approvals, co, members)
+		if err != nil {
+			c.logger.Error("Rule check failed", "rule", name, "error", err)
+			eval.ruleErrors[name] = err
+			continue
+		}
+		eval.rules[name] = ruleEntry{Fingerprint: fingerprints[i], Failure: failure}
+		if failure != nil {
+			eval.failures = append(eval.failures, *failure)
+		}
+	}
 
-	return &CheckResult{
-		Passed:   passed,
-		Failures: failures,
-		Summary:  summary,
-	}, nil
+	return eval, nil
+}
+
+// loadCodeowners fetches the project members and the CODEOWNERS patterns matching the changed paths
+func (c *Checker) loadCodeowners(ctx context.Context, data *mergeRequestData, ref string) ([]*codeowners.PatternGroup, []*gitlabapi.ProjectMember, *CodeownersSource, error) {
+	ctx, span := tracing.Start(ctx, "conformity.codeowners", trace.WithAttributes(attribute.String("git.ref", ref)))
+	defer span.End()
+
+	paths, err := data.getPaths(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, nil, nil, err
+	}
+	// Get project members
+	members, err := c.gitlabClient.ListProjectMembers(ctx, data.projectID)
+	if err != nil {
+		c.logger.Info("Failed to list project members", "error", err)
+	}
+	// Get CODEOWNERS file from repository
+	co, source, err := c.getCodeowners(ctx, data.projectID, ref, members, paths)
+	if err != nil {
+		c.logger.Info("No CODEOWNERS file found in repository, skipping", "error", err)
+	}
+	span.SetAttributes(attribute.Int("codeowners.patterns", len(co)))
+
+	return co, members, source, nil
+}
+
+// mergeRequestData holds the merge request being checked and fetches the data
+// only some rules need on first use
+type mergeRequestData struct {
+	client    *gitlab.Client
+	projectID interface{}
+	mrID      int
+	mr        *gitlabapi.MergeRequest
+	approvals *common.Approvals
+
+	commits        []*gitlabapi.Commit
+	commitsFetched bool
+	paths          []string
+	pathsFetched   bool
 }
 
-// fetchMergeRequestData retrieves merge request and commit data
-func (c *Checker) fetchMergeRequestData(projectID interface{}, mrID int, finalConfig config.RulesConfig) (*gitlabapi.MergeRequest, []*gitlabapi.Commit, *common.Approvals, error) {
+// fetchMergeRequestData retrieves the merge request and its approvals
+func (c *Checker) fetchMergeRequestData(ctx context.Context, projectID interface{}, mrID int, finalConfig config.RulesConfig) (*mergeRequestData, error) {
 	// Get merge request details
-	mr, err := c.gitlabClient.GetMergeRequest(projectID, mrID)
+	mr, err := c.gitlabClient.GetMergeRequest(ctx, projectID, mrID)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get merge request: %w", err)
+		return nil, fmt.Errorf("failed to get merge request: %w", err)
 	}
 	// Get mr approvers
-	approvals, err := c.gitlabClient.ListMergeRequestApprovals(projectID, mrID, mr.Author.ID, finalConfig.Approvals.ExcludeCreatorFromCount)
+	approvals, err := c.gitlabClient.ListMergeRequestApprovals(ctx, projectID, mrID, mr.Author.ID, finalConfig.Approvals.ExcludeCreatorFromCount)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get merge request: %w", err)
+		return nil, fmt.Errorf("failed to get merge request approvals: %w", err)
 	}
 
-	// Get commits for commit-related rules
-	commits, err := c.gitlabClient.ListMergeRequestCommits(projectID, mrID)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get commits: %w", err)
+	return &mergeRequestData{
+		client:    c.gitlabClient,
+		projectID: projectID,
+		mrID:      mrID,
+		mr:        mr,
+		approvals: approvals,
+	}, nil
+}
+
+// getCommits returns the commits of the merge request, fetching them once
+func (d *mergeRequestData) getCommits(ctx context.Context) ([]*gitlabapi.Commit, error) {
+	if !d.commitsFetched {
+		commits, err := d.client.ListMergeRequestCommits(ctx, d.projectID, d.mrID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commits: %w", err)
+		}
+		d.commits, d.commitsFetched = commits, true
 	}
+	return d.commits, nil
+}
 
-	return mr, commits, approvals, nil
+// getPaths returns the paths changed by the merge request, fetching them once
+func (d *mergeRequestData) getPaths(ctx context.Context) ([]string, error) {
+	if !d.pathsFetched {
+		paths, err := d.client.GetAllDiffsPaths(ctx, d.projectID, d.mrID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get diff paths: %w", err)
+		}
+		d.paths, d.pathsFetched = paths, true
+	}
+	return d.paths, nil
 }
 
-// executeRuleChecks runs all rules and collects failures
-func (c *Checker) executeRuleChecks(rulesList []rules.Rule, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, codeowners []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) []RuleFailure {
-	var failures []RuleFailure
+// executeRuleCheck runs a rule and returns its failure, or nil when it passed
+func (c *Checker) executeRuleCheck(ctx context.Context, rule rules.Rule, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, codeowners []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleFailure, error) {
+	c.logger.Debug("Checking rule", "rule", rule.Name())
+
+	ctx, span := tracing.Start(ctx, "rule.check", trace.WithAttributes(attribute.String("rule.name", rule.Name())))
+	result, err := rule.Check(ctx, mr, commits, approvals, codeowners, members)
+	if result != nil {
+		span.SetAttributes(attribute.Bool("rule.passed", result.Passed))
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	if result.Passed {
+		return nil, nil
+	}
+
+	return &RuleFailure{
+		RuleName:   rule.Name(),
+		Severity:   rule.Severity(),
+		Error:      result.Error,
+		Suggestion: result.Suggestion,
+	}, nil
+}
 
-	for _, rule := range rulesList {
-		c.logger.Debug("Checking rule", "rule", rule.Name())
+// observeRuleResults counts the rules that ran in an evaluation, reused outcomes are not counted
+func observeRuleResults(eval *evaluation) {
+	failed := make(map[string]bool, len(eval.failures))
+	for _, failure := range eval.failures {
+		failed[failure.RuleName] = true
+	}
 
-		result, err := rule.Check(mr, commits, approvals, codeowners, members)
-		if err != nil {
-			c.logger.Error("Rule check failed", "rule", rule.Name(), "error", err)
+	for _, name := range eval.ruleNames {
+		if eval.reused[name] {
 			continue
 		}
-
-		if !result.Passed {
-			failures = append(failures, RuleFailure{
-				RuleName:   rule.Name(),
-				Severity:   rule.Severity(),
-				Error:      result.Error,
-				Suggestion: result.Suggestion,
-			})
+		result := "passed"
+		if eval.ruleErrors[name] != nil {
+			result = "error"
+		} else if failed[name] {
+			result = "failed"
 		}
+		metrics.RuleResults.WithLabelValues(name, result).Inc()
 	}
+}
 
-	return failures
+// countBlocking returns the number of failures that fail the merge request
+func countBlocking(failures []RuleFailure) int {
+	count := 0
+	for _, failure := range failures {
+		if failure.Severity.IsBlocking() {
+			count++
+		}
+	}
+	return count
 }
 
-func (c *Checker) getCodeowners(projectID interface{}, mrID int, members []*gitlabapi.ProjectMember) ([]*codeowners.PatternGroup, error) {
+// getCodeowners reads the CODEOWNERS file at ref and returns the patterns matching paths
+func (c *Checker) getCodeowners(ctx context.Context, projectID interface{}, ref string, members []*gitlabapi.ProjectMember, paths []string) ([]*codeowners.PatternGroup, *CodeownersSource, error) {
 	// Try to get CODEOWNERS file from repository
-	co, err := c.gitlabClient.GetCodeownersFile(projectID)
+	co, err := c.gitlabClient.GetCodeownersFile(ctx, projectID, ref)
 	if err != nil {
 		c.logger.Debug("No CODEOWNERS file found in repository, skipping", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
+	source := &CodeownersSource{Path: co.FilePath, Ref: co.Ref}
 
 	// Decode the base64 content
 	decoded, err := base64.StdEncoding.DecodeString(co.Content)
 	if err != nil {
-		c.logger.Warn("Failed to decode config file from repository, using default config", "error", err)
-		return nil, fmt.Errorf("failed to decode config: %w", err)
+		c.logger.Warn("Failed to decode CODEOWNERS file", "path", co.FilePath, "error", err)
+		return nil, source, fmt.Errorf("failed to decode CODEOWNERS: %w", err)
 	}
 
 	parser := codeowners.NewCodeownersParser(c.logger)
@@ -168,12 +513,21 @@ func (c *Checker) getCodeowners(projectID interface{}, mrID int, members []*gitl
 
 	cos, err := parser.Parse(strings.NewReader(string(decoded)))
 	if err != nil {
-		c.logger.Fatal("Error parsing CODEOWNERS: %v", err)
+		return nil, source, fmt.Errorf("failed to parse CODEOWNERS: %w", err)
 	}
 
-	paths, err := c.gitlabClient.GetAllDiffsPaths(projectID, mrID)
-	if err != nil {
-		log.Fatalf("Error obtaining diff paths: %v", err)
+	// Owners that are not project members may be groups, resolve them to their members
+	if names := cos.GroupOwnerNames(); len(names) > 0 {
+		if groups := c.groupResolver.Resolve(ctx, names); len(groups) > 0 {
+			for group := range groups {
+				parser.AddAccessibleGroup(group)
+			}
+			cos, err = parser.Parse(strings.NewReader(string(decoded)))
+			if err != nil {
+				return nil, source, fmt.Errorf("failed to parse CODEOWNERS: %w", err)
+			}
+			cos.SetGroupMembers(groups)
+		}
 	}
 
 	// Get only active patterns (final effective patterns)
@@ -187,5 +541,5 @@ func (c *Checker) getCodeowners(projectID interface{}, mrID int, members []*gitl
 		return sortedGroups[i].Pattern < sortedGroups[j].Pattern
 	})
 
-	return sortedGroups, nil
+	return sortedGroups, source, nil
 }
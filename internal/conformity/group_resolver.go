@@ -0,0 +1,90 @@
+package conformity
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/gitlab"
+	"gitlab-mr-conformity-bot/pkg/logger"
+
+	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+)
+
+// groupMembersTTL is how long resolved group memberships are reused
+const groupMembersTTL = 5 * time.Minute
+
+// groupResolver resolves CODEOWNERS group owners to their members, caching the
+// result per group. Names that are not groups are cached as well.
+type groupResolver struct {
+	client *gitlab.Client
+	ttl    time.Duration
+	logger *logger.Logger
+
+	mu      sync.Mutex
+	entries map[string]groupEntry
+}
+
+type groupEntry struct {
+	members []string
+	isGroup bool
+	expires time.Time
+}
+
+func newGroupResolver(client *gitlab.Client, ttl time.Duration, log *logger.Logger) *groupResolver {
+	return &groupResolver{
+		client:  client,
+		ttl:     ttl,
+		logger:  log,
+		entries: make(map[string]groupEntry),
+	}
+}
+
+// Resolve returns the members of every name that is a GitLab group
+func (r *groupResolver) Resolve(ctx context.Context, names []string) map[string][]string {
+	groups := make(map[string][]string)
+
+	for _, name := range names {
+		entry, err := r.lookup(ctx, name)
+		if err != nil {
+			r.logger.Warn("Failed to resolve CODEOWNERS group", "group", name, "error", err)
+			continue
+		}
+		if entry.isGroup {
+			groups[name] = entry.members
+		}
+	}
+
+	return groups
+}
+
+func (r *groupResolver) lookup(ctx context.Context, name string) (groupEntry, error) {
+	r.mu.Lock()
+	entry, ok := r.entries[name]
+	r.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry, nil
+	}
+
+	members, err := r.client.ListGroupMemberUsernames(ctx, name)
+	switch {
+	case errors.Is(err, gitlabapi.ErrNotFound):
+		entry = groupEntry{}
+	case err != nil:
+		return groupEntry{}, err
+	default:
+		if members == nil {
+			members = []string{}
+		}
+		entry = groupEntry{members: members, isGroup: true}
+	}
+	entry.expires = time.Now().Add(r.ttl)
+
+	r.mu.Lock()
+	r.entries[name] = entry
+	r.mu.Unlock()
+
+	return entry, nil
+}
@@ -0,0 +1,139 @@
+package conformity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/gitlab"
+	"gitlab-mr-conformity-bot/pkg/logger"
+)
+
+// fakeGroupsAPI serves the group endpoints used to resolve CODEOWNERS groups
+type fakeGroupsAPI struct {
+	// subgroups and members are keyed by full group path; unknown groups are not found
+	subgroups map[string][]string
+	members   map[string][]string
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (f *fakeGroupsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+
+	rest, _ := strings.CutPrefix(r.URL.EscapedPath(), "/api/v4/groups/")
+	escaped, endpoint, _ := strings.Cut(rest, "/")
+	group, _ := url.PathUnescape(escaped)
+	if _, ok := f.members[group]; !ok {
+		http.Error(w, `{"message":"404 Group Not Found"}`, http.StatusNotFound)
+		return
+	}
+
+	var body []map[string]interface{}
+	switch endpoint {
+	case "descendant_groups":
+		for _, subgroup := range f.subgroups[group] {
+			body = append(body, map[string]interface{}{"full_path": subgroup})
+		}
+	case "members/all":
+		for _, username := range f.members[group] {
+			body = append(body, map[string]interface{}{"username": username, "state": "active"})
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeGroupsAPI) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func newTestGroupResolver(t *testing.T, api *fakeGroupsAPI) *groupResolver {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	client, err := gitlab.NewClient("token", server.URL, false, 5*time.Second, gitlab.RateLimitConfig{})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return newGroupResolver(client, time.Minute, logger.New())
+}
+
+func TestGroupResolver_Resolve(t *testing.T) {
+	api := &fakeGroupsAPI{
+		subgroups: map[string][]string{"backend": {"backend/api"}},
+		members: map[string][]string{
+			"backend":      {"alice", "bob"},
+			"backend/api":  {"carol", "alice"},
+			"org/platform": {"dave"},
+			"empty":        {},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		names []string
+		want  map[string][]string
+	}{
+		{name: "user is not a group", names: []string{"mallory"}, want: map[string][]string{}},
+		{name: "group", names: []string{"org/platform"}, want: map[string][]string{"org/platform": {"dave"}}},
+		{name: "group with subgroups", names: []string{"backend"}, want: map[string][]string{"backend": {"alice", "bob", "carol"}}},
+		{name: "nested group", names: []string{"backend/api"}, want: map[string][]string{"backend/api": {"alice", "carol"}}},
+		{name: "group without members", names: []string{"empty"}, want: map[string][]string{"empty": {}}},
+		{
+			name:  "users and groups",
+			names: []string{"backend/api", "mallory", "org/platform"},
+			want:  map[string][]string{"backend/api": {"alice", "carol"}, "org/platform": {"dave"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTestGroupResolver(t, api).Resolve(context.Background(), tt.names)
+			for _, members := range got {
+				sort.Strings(members)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGroupResolver_CachesLookups(t *testing.T) {
+	api := &fakeGroupsAPI{members: map[string][]string{"backend": {"alice"}}}
+	resolver := newTestGroupResolver(t, api)
+	names := []string{"backend", "mallory"}
+
+	resolver.Resolve(context.Background(), names)
+	requests := api.requestCount()
+	if requests == 0 {
+		t.Fatal("expected the groups to be looked up")
+	}
+
+	// Groups and names that are not groups are both cached
+	got := resolver.Resolve(context.Background(), names)
+	if more := api.requestCount() - requests; more != 0 {
+		t.Errorf("expected cached lookups, got %d more requests", more)
+	}
+	if !reflect.DeepEqual(got, map[string][]string{"backend": {"alice"}}) {
+		t.Errorf("expected cached members, got %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package codeowners
+
+import "sort"
+
+// GroupOwnerNames returns the names of @-owners that are not known users and
+// may therefore refer to GitLab groups
+func (c *CODEOWNERSFile) GroupOwnerNames() []string {
+	seen := make(map[string]bool)
+	c.forEachOwner(func(owner *Owner) {
+		if owner.IsGroup && !owner.IsValid {
+			seen[owner.Name] = true
+		}
+	})
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetGroupMembers records the members of resolved group owners so approvals
+// from any member count for the group
+func (c *CODEOWNERSFile) SetGroupMembers(groups map[string][]string) {
+	c.forEachOwner(func(owner *Owner) {
+		if !owner.IsGroup {
+			return
+		}
+		if members, ok := groups[owner.Name]; ok {
+			owner.Members = members
+		}
+	})
+}
+
+func (c *CODEOWNERSFile) forEachOwner(fn func(owner *Owner)) {
+	visitRules := func(rules []Rule) {
+		for i := range rules {
+			for j := range rules[i].Owners {
+				fn(&rules[i].Owners[j])
+			}
+		}
+	}
+
+	visitRules(c.DefaultRules)
+	for i := range c.Sections {
+		for j := range c.Sections[i].DefaultOwners {
+			fn(&c.Sections[i].DefaultOwners[j])
+		}
+		visitRules(c.Sections[i].Rules)
+	}
+}
@@ -0,0 +1,72 @@
+package codeowners
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-mr-conformity-bot/pkg/logger"
+)
+
+func parseCodeowners(t *testing.T, content string, users ...string) *CODEOWNERSFile {
+	t.Helper()
+	parser := NewCodeownersParser(logger.New())
+	for _, user := range users {
+		parser.AddAccessibleUser(user)
+	}
+	file, err := parser.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to parse CODEOWNERS: %v", err)
+	}
+	return file
+}
+
+func TestGroupOwnerNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "project member", content: "*.go @alice\n", want: []string{}},
+		{name: "group", content: "*.go @backend\n", want: []string{"backend"}},
+		{name: "nested group", content: "*.go @org/backend/api\n", want: []string{"org/backend/api"}},
+		{name: "email and role", content: "*.go alice@example.com @@maintainers\n", want: []string{}},
+		{
+			name:    "section default owners, deduplicated and sorted",
+			content: "[Docs] @writers\ndocs/ @org/docs @alice\n*.md @writers\n",
+			want:    []string{"org/docs", "writers"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseCodeowners(t, tt.content, "alice").GroupOwnerNames()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetGroupMembers(t *testing.T) {
+	file := parseCodeowners(t, "[Backend] @org/backend\n*.go @org/backend @alice\n", "alice")
+	file.SetGroupMembers(map[string][]string{"org/backend": {"bob", "carol"}})
+
+	var members []string
+	file.forEachOwner(func(owner *Owner) {
+		switch owner.Name {
+		case "org/backend":
+			if !reflect.DeepEqual(owner.Members, []string{"bob", "carol"}) {
+				t.Errorf("expected the group members to be set, got %v", owner.Members)
+			}
+			members = append(members, owner.Name)
+		case "alice":
+			if owner.Members != nil {
+				t.Errorf("expected no members for an owner that is not a resolved group, got %v", owner.Members)
+			}
+		}
+	})
+	if len(members) != 2 {
+		t.Errorf("expected the group as section default and rule owner, got %d", len(members))
+	}
+}
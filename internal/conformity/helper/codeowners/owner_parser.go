@@ -129,10 +129,9 @@ func (p *Parser) isOwnerAccessible(ownerStr string) bool {
 				return accessible
 			}
 		} else {
-			if p.HasAccessibleOwners() {
-				accessible := p.accessibleOwners.Users[owner.Name]
-				p.logger.Debug("Checking user in accessible users", "user", owner.Name, "accessible", accessible)
-				return accessible
+			if p.HasAccessibleOwners() && p.accessibleOwners.Users[owner.Name] {
+				p.logger.Debug("Checking user in accessible users", "user", owner.Name, "accessible", true)
+				return true
 			}
 		}
 		// Default behavior based on validation mode
@@ -147,10 +146,9 @@ func (p *Parser) isOwnerAccessible(ownerStr string) bool {
 				return accessible
 			}
 		} else {
-			if p.HasAccessibleOwners() {
-				accessible := p.accessibleOwners.Users[owner.Name]
-				p.logger.Debug("Checking user in accessible users", "user", owner.Name, "accessible", accessible)
-				return accessible
+			if p.HasAccessibleOwners() && p.accessibleOwners.Users[owner.Name] {
+				p.logger.Debug("Checking user in accessible users", "user", owner.Name, "accessible", true)
+				return true
 			}
 		}
 		if p.HasAccessibleOwners() {
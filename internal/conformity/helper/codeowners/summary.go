@@ -99,13 +99,7 @@ func createPatternSummary(pattern *PatternGroup, approvals *common.Approvals, me
 			summary.OwnerStatuses = append(summary.OwnerStatuses, ownerStatus)
 
 			// Add to allowed approvers list - expand roles to individual members
-			if owner.IsRole {
-				roleMembers := getRoleMembers(owner.Name, members)
-				summary.AllowedApprovers = append(summary.AllowedApprovers, roleMembers...)
-			} else {
-				// Since owners are pre-filtered to accessible members, we can directly add them
-				summary.AllowedApprovers = append(summary.AllowedApprovers, owner.Name)
-			}
+			summary.AllowedApprovers = append(summary.AllowedApprovers, ownerApprovers(owner, members)...)
 		}
 
 		return summary
@@ -113,36 +107,41 @@ func createPatternSummary(pattern *PatternGroup, approvals *common.Approvals, me
 
 	// Build allowed approvers list - since owners are pre-filtered, all should be valid
 	for _, owner := range pattern.Owners {
-		if owner.IsRole {
-			roleMembers := getRoleMembers(owner.Name, members)
-			summary.AllowedApprovers = append(summary.AllowedApprovers, roleMembers...)
-		} else {
-			summary.AllowedApprovers = append(summary.AllowedApprovers, owner.Name)
-		}
+		summary.AllowedApprovers = append(summary.AllowedApprovers, ownerApprovers(owner, members)...)
 	}
 
 	// Check each owner's approval status for regular patterns
+	counted := make(map[int]bool)
 	for _, owner := range pattern.Owners {
 		ownerStatus := OwnerApprovalStatus{
 			Owner: owner,
 		}
 
-		// Find if this owner has approved
+		// Find if this owner has approved, every approving member of a group owner counts
 		if approvals != nil && approvals.ApprovalsInfo != nil {
 			for userID, approval := range approvals.ApprovalsInfo {
-				if matchesOwner(owner, approval, members) {
-					ownerStatus.HasApproved = (approval.Status == "approved")
+				if !matchesOwner(owner, approval, members) {
+					continue
+				}
+
+				approved := approval.Status == "approved"
+				if ownerStatus.ApprovalInfo == nil || (approved && !ownerStatus.HasApproved) {
+					ownerStatus.HasApproved = approved
 					ownerStatus.ApprovalInfo = &common.ApprovalInfo{
 						UserID:    userID,
 						Username:  approval.Username,
 						Status:    approval.Status,
 						UpdatedAt: approval.UpdatedAt,
 					}
+				}
 
-					// Count valid approvals - since owners are pre-filtered, all approvals are valid
-					if approval.Status == "approved" {
-						summary.ApprovedCount++
-					}
+				// Count valid approvals - since owners are pre-filtered, all approvals are valid
+				if approved && !counted[userID] {
+					counted[userID] = true
+					summary.ApprovedCount++
+				}
+
+				if !isResolvedGroup(owner) {
 					break
 				}
 			}
@@ -151,8 +150,22 @@ func createPatternSummary(pattern *PatternGroup, approvals *common.Approvals, me
 		summary.OwnerStatuses = append(summary.OwnerStatuses, ownerStatus)
 	}
 
+	// Prefer GitLab's own evaluation of the matching code owner rule when available
+	if rule, ok := approvals.CodeOwnerRule(pattern.SectionName, pattern.Pattern); ok {
+		if len(summary.AllowedApprovers) == 0 {
+			summary.AllowedApprovers = append(summary.AllowedApprovers, rule.EligibleApprovers...)
+		}
+		if rule.Approved {
+			summary.ApprovedByRule = true
+			summary.ApprovedCount = max(summary.ApprovedCount, len(rule.ApprovedBy))
+		}
+	}
+
 	summary.RemainingCount = max(0, summary.RequiredCount-summary.ApprovedCount)
-	summary.IsFullyApproved = summary.ApprovedCount >= summary.RequiredCount
+	summary.IsFullyApproved = summary.ApprovedCount >= summary.RequiredCount || summary.ApprovedByRule
+	if summary.IsFullyApproved {
+		summary.RemainingCount = 0
+	}
 
 	return summary
 }
@@ -223,10 +236,38 @@ func matchesOwner(owner Owner, approval common.ApprovalInfo, members []*gitlabap
 		return false
 	}
 
+	// Handle group matching - any member of a resolved group is an owner
+	if isResolvedGroup(owner) {
+		for _, member := range owner.Members {
+			if strings.EqualFold(member, approval.Username) {
+				return true
+			}
+		}
+		return false
+	}
+
 	// For individual usernames
 	return strings.EqualFold(owner.Name, approval.Username)
 }
 
+// isResolvedGroup reports whether the owner is a GitLab group whose members are known
+func isResolvedGroup(owner Owner) bool {
+	return owner.IsGroup && owner.Members != nil
+}
+
+// ownerApprovers expands an owner to the usernames allowed to approve for it
+func ownerApprovers(owner Owner, members []*gitlabapi.ProjectMember) []string {
+	switch {
+	case owner.IsRole:
+		return getRoleMembers(owner.Name, members)
+	case isResolvedGroup(owner):
+		return owner.Members
+	default:
+		// Since owners are pre-filtered to accessible members, we can directly add them
+		return []string{owner.Name}
+	}
+}
+
 // Generate aggregated markdown table with merged sections (by section name AND owners)
 func (s *CodeOwnersSummary) GenerateAggregatedOutput() (string, string) {
 	var aggregatedTable strings.Builder
@@ -294,7 +335,7 @@ func (s *CodeOwnersSummary) GenerateAggregatedOutput() (string, string) {
 			}
 		}
 		section.IsAutoApproved = isAutoApproved
-		section.IsFullyApproved = section.ApprovedCount >= section.RequiredCount || isAutoApproved || len(section.AllowedApprovers) == 0
+		section.IsFullyApproved = section.ApprovedCount >= section.RequiredCount || isAutoApproved || len(section.AllowedApprovers) == 0 || approvedByRules(section.PatternSummaries)
 	}
 
 	// Build the complete table with merged sections
@@ -375,6 +416,16 @@ func (s *CodeOwnersSummary) GenerateAggregatedOutput() (string, string) {
 	return aggregatedTable.String(), suggestion
 }
 
+// approvedByRules reports whether GitLab considers every pattern of a section approved
+func approvedByRules(patterns []PatternApprovalSummary) bool {
+	for _, pattern := range patterns {
+		if !pattern.ApprovedByRule {
+			return false
+		}
+	}
+	return len(patterns) > 0
+}
+
 // common function to count approvals for specific owners
 func countApprovalsForOwners(allowedApprovers []string, approvals *common.Approvals) int {
 	if approvals == nil || approvals.ApprovalsInfo == nil {
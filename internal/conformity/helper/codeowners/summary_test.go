@@ -0,0 +1,59 @@
+package codeowners
+
+import (
+	"testing"
+
+	"gitlab-mr-conformity-bot/internal/conformity/helper/common"
+)
+
+func TestCreateCodeOwnersSummary_ApprovedByRule(t *testing.T) {
+	patterns := []*PatternGroup{
+		{Pattern: "*.go", SectionName: "Default", RequiredApprovals: 1, Owners: []Owner{{Name: "alice"}}},
+		{Pattern: "docs/", SectionName: "Docs", RequiredApprovals: 1, Owners: []Owner{{Name: "bob"}}},
+	}
+	approvals := &common.Approvals{
+		ApprovalsInfo: map[int]common.ApprovalInfo{},
+		Source:        common.ApprovalSourceAPI,
+		Rules: []common.ApprovalRule{
+			{Name: "*.go", RuleType: "code_owner", Section: "codeowners", ApprovalsRequired: 1, Approved: true, ApprovedBy: []string{"carol"}},
+			{Name: "docs/", RuleType: "code_owner", Section: "Docs", ApprovalsRequired: 1},
+		},
+	}
+
+	summary := CreateCodeOwnersSummary(patterns, approvals, nil)
+
+	if !summary.Patterns[0].IsFullyApproved || !summary.Patterns[0].ApprovedByRule {
+		t.Errorf("expected pattern approved by GitLab rule, got %+v", summary.Patterns[0])
+	}
+	if summary.Patterns[1].IsFullyApproved {
+		t.Errorf("expected docs pattern to still need approval")
+	}
+	if summary.AllPatternsApproved {
+		t.Error("expected summary to require approvals")
+	}
+}
+
+func TestCreateCodeOwnersSummary_GroupMembers(t *testing.T) {
+	patterns := []*PatternGroup{
+		{Pattern: "/backend/", SectionName: "Default", RequiredApprovals: 2, Owners: []Owner{
+			{Name: "org/backend-team", IsGroup: true, IsValid: true, Members: []string{"alice", "bob"}},
+		}},
+	}
+	approvals := &common.Approvals{
+		ApprovalsInfo: map[int]common.ApprovalInfo{
+			1: {UserID: 1, Username: "alice", Status: "approved"},
+			2: {UserID: 2, Username: "bob", Status: "approved"},
+			3: {UserID: 3, Username: "mallory", Status: "approved"},
+		},
+	}
+
+	summary := CreateCodeOwnersSummary(patterns, approvals, nil)
+
+	pattern := summary.Patterns[0]
+	if pattern.ApprovedCount != 2 || !pattern.IsFullyApproved {
+		t.Errorf("expected both group members to count, got %d approvals", pattern.ApprovedCount)
+	}
+	if len(pattern.AllowedApprovers) != 2 {
+		t.Errorf("expected group expanded to its members, got %v", pattern.AllowedApprovers)
+	}
+}
@@ -14,8 +14,9 @@ type Owner struct {
 	IsRole   bool
 	IsGroup  bool
 	IsNested bool
-	IsValid  bool   // Track if owner is accessible/valid
-	Original string // Original string representation
+	IsValid  bool     // Track if owner is accessible/valid
+	Original string   // Original string representation
+	Members  []string // Usernames of the members of a group owner
 }
 
 // OwnerType represents the type of owner
@@ -132,6 +133,8 @@ type PatternApprovalSummary struct {
 	IsOptional       bool
 	IsExclusion      bool
 	AllowedApprovers []string
+	// ApprovedByRule is set when GitLab reports the matching code owner rule as approved
+	ApprovedByRule bool
 }
 
 type CodeOwnersSummary struct {
@@ -42,7 +42,52 @@ type ApprovalInfo struct {
 	UpdatedAt *time.Time
 }
 
+// ApprovalSource tells where approval information was read from
+type ApprovalSource string
+
+const (
+	// ApprovalSourceAPI means approvals come from the merge request approvals API
+	ApprovalSourceAPI ApprovalSource = "api"
+	// ApprovalSourceNotes means approvals were reconstructed from system notes
+	ApprovalSourceNotes ApprovalSource = "notes"
+)
+
+// ApprovalRule is the state of a GitLab approval rule on a merge request
+type ApprovalRule struct {
+	ID                int
+	Name              string
+	RuleType          string // "regular", "code_owner", "report_approver" or "any_approver"
+	Section           string
+	ApprovalsRequired int
+	Approved          bool
+	EligibleApprovers []string
+	ApprovedBy        []string
+}
+
 type Approvals struct {
 	ApprovalsCount int
 	ApprovalsInfo  map[int]ApprovalInfo
+	// Source tells whether approvals come from the API or from system notes
+	Source ApprovalSource
+	// Rules holds the approval rules of the merge request, empty when the
+	// approval state is not available (e.g. GitLab Free or note fallback)
+	Rules []ApprovalRule
+}
+
+// CodeOwnerRule returns the GitLab code owner rule for a CODEOWNERS pattern in a section
+func (a *Approvals) CodeOwnerRule(section, pattern string) (*ApprovalRule, bool) {
+	if a == nil {
+		return nil, false
+	}
+	for i := range a.Rules {
+		rule := &a.Rules[i]
+		if rule.RuleType != "code_owner" || rule.Name != pattern {
+			continue
+		}
+		// GitLab names the section of rules outside of any section "codeowners"
+		if strings.EqualFold(rule.Section, section) || (section == "Default" && strings.EqualFold(rule.Section, "codeowners")) {
+			return rule, true
+		}
+	}
+	return nil, false
 }
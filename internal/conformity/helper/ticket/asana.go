@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/internal/metrics"
 	"net/http"
 	"regexp"
 	"time"
@@ -33,6 +34,11 @@ func NewAsanaValidator(cfg config.AsanaValidatorConfig, apiToken string) *AsanaV
 	}
 }
 
+// SetTimeout bounds every single request to the Asana API
+func (v *AsanaValidator) SetTimeout(timeout time.Duration) {
+	v.httpClient.Timeout = timeout
+}
+
 func (v *AsanaValidator) Name() string {
 	return "Asana"
 }
@@ -111,7 +117,19 @@ func (v *AsanaValidator) ValidateTicket(ctx context.Context, info *TicketInfo) V
 	}
 }
 
-func (v *AsanaValidator) checkTaskExists(ctx context.Context, taskID string) (bool, error) {
+func (v *AsanaValidator) checkTaskExists(ctx context.Context, taskID string) (exists bool, err error) {
+	start := time.Now()
+	defer func() {
+		result := "found"
+		switch {
+		case err != nil:
+			result = "error"
+		case !exists:
+			result = "not_found"
+		}
+		metrics.AsanaRequestDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	}()
+
 	url := fmt.Sprintf("%s/api/1.0/tasks/%s", v.baseURL, taskID)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -12,6 +12,9 @@ func BuildTicketValidators(jiraCfg config.JiraConfig, asanaCfg config.AsanaValid
 
 	if len(asanaCfg.Keys) > 0 && asanaCfg.Keys[0] != "" {
 		asanaValidator := NewAsanaValidator(asanaCfg, integrations.Asana.APIToken)
+		if integrations.Asana.Timeout > 0 {
+			asanaValidator.SetTimeout(integrations.Asana.Timeout)
+		}
 		manager.AddValidator(asanaValidator)
 	}
 
@@ -1,6 +1,13 @@
 package ticket
 
-import "context"
+import (
+	"context"
+
+	"gitlab-mr-conformity-bot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
 
 type ValidatorManager struct {
 	validators []TicketValidator
@@ -38,7 +45,7 @@ func (m *ValidatorManager) ValidateMessage(ctx context.Context, message string)
 	for _, validator := range m.validators {
 		if validator.ContainsTicket(message) {
 			ticket := validator.ExtractTicket(message)
-			result := validator.ValidateTicket(ctx, ticket)
+			result := m.validateTicket(ctx, validator, ticket)
 			results[validator.Name()] = result
 			anyFound = true
 			if result.Valid {
@@ -53,3 +60,13 @@ func (m *ValidatorManager) ValidateMessage(ctx context.Context, message string)
 		AllMissing: !anyFound,
 	}
 }
+
+// validateTicket validates a ticket within a span, as validators may call external APIs
+func (m *ValidatorManager) validateTicket(ctx context.Context, validator TicketValidator, ticket *TicketInfo) ValidationResult {
+	ctx, span := tracing.Start(ctx, "ticket.validate", trace.WithAttributes(attribute.String("ticket.validator", validator.Name())))
+	defer span.End()
+
+	result := validator.ValidateTicket(ctx, ticket)
+	span.SetAttributes(attribute.Bool("ticket.valid", result.Valid))
+	return result
+}
@@ -0,0 +1,56 @@
+package conformity
+
+import (
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/conformity/helper/common"
+	"gitlab-mr-conformity-bot/internal/history"
+
+	"github.com/google/uuid"
+	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+)
+
+// newHistoryRecord describes a check run for the audit trail, with the outcome of every enabled rule
+func newHistoryRecord(mr *gitlabapi.MergeRequest, configVersion string, approvals *common.Approvals, eval *evaluation, result *CheckResult, startedAt time.Time) *history.Record {
+	failures := make(map[string]RuleFailure, len(eval.failures))
+	for _, failure := range eval.failures {
+		failures[failure.RuleName] = failure
+	}
+
+	ruleResults := make([]history.RuleResult, 0, len(eval.ruleNames))
+	for _, name := range eval.ruleNames {
+		ruleResult := history.RuleResult{Name: name, Passed: true, Reused: eval.reused[name]}
+		if failure, ok := failures[name]; ok {
+			ruleResult.Passed = false
+			ruleResult.Severity = failure.Severity.String()
+			ruleResult.Errors = failure.Error
+			ruleResult.Suggestions = failure.Suggestion
+		}
+		if err, ok := eval.ruleErrors[name]; ok {
+			ruleResult.Passed = false
+			ruleResult.Errors = []string{"Rule could not be checked: " + err.Error()}
+		}
+		ruleResults = append(ruleResults, ruleResult)
+	}
+
+	var author string
+	if mr.Author != nil {
+		author = mr.Author.Username
+	}
+
+	return &history.Record{
+		ID:              uuid.New().String(),
+		ProjectID:       mr.ProjectID,
+		MergeRequestIID: mr.IID,
+		Author:          author,
+		HeadSHA:         mr.SHA,
+		TargetBranch:    mr.TargetBranch,
+		ConfigVersion:   configVersion,
+		Passed:          result.Passed,
+		Rules:           ruleResults,
+		Approvals:       approvals,
+		ConfigWarnings:  result.ConfigWarnings,
+		StartedAt:       startedAt,
+		CompletedAt:     time.Now(),
+	}
+}
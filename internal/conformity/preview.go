@@ -0,0 +1,130 @@
+package conformity
+
+import (
+	"context"
+	"fmt"
+
+	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/internal/gitlab"
+)
+
+// previewFiles are the repository files whose proposed content is previewed
+var previewFiles = append([]string{".mr-conform.yaml"}, gitlab.CodeownersPaths...)
+
+// ConfigPreview describes how results would change if the configuration
+// proposed by a merge request was in effect
+type ConfigPreview struct {
+	// Files lists the configuration files changed by the merge request
+	Files []string
+	// Changes lists the rules whose result differs, in rule order
+	Changes []PreviewChange
+	// Warnings describes proposed configuration files that would be ignored
+	Warnings []string
+}
+
+// PreviewChange is the result of a single rule under the current and the proposed configuration
+type PreviewChange struct {
+	RuleName string
+	Current  string
+	Proposed string
+}
+
+// changedConfigFiles returns the configuration files found among the changed paths
+func changedConfigFiles(paths []string) []string {
+	var changed []string
+	for _, file := range previewFiles {
+		for _, path := range paths {
+			if path == file {
+				changed = append(changed, file)
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// previewConfig evaluates the merge request with the configuration files of its head commit
+func (c *Checker) previewConfig(ctx context.Context, data *mergeRequestData, currentConfig config.RulesConfig, files []string, current *evaluation) (*ConfigPreview, error) {
+	mr := data.mr
+	approvals := data.approvals
+
+	// Commits of fork merge requests are available in the target project
+	effective, err := c.configLoader.LoadEffectiveConfigAt(ctx, data.projectID, mr.SHA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load proposed configuration: %w", err)
+	}
+	proposedConfig := effective.Rules
+
+	if proposedConfig.Approvals.ExcludeCreatorFromCount != currentConfig.Approvals.ExcludeCreatorFromCount {
+		approvals, err = c.gitlabClient.ListMergeRequestApprovals(ctx, data.projectID, data.mrID, mr.Author.ID, proposedConfig.Approvals.ExcludeCreatorFromCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get merge request approvals: %w", err)
+		}
+	}
+
+	// The proposed configuration is always evaluated in full
+	proposed, err := c.evaluate(ctx, data, approvals, proposedConfig, mr.SHA, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigPreview{
+		Files:    files,
+		Changes:  compareEvaluations(current, proposed),
+		Warnings: effective.Warnings,
+	}, nil
+}
+
+// compareEvaluations lists the rules whose outcome differs between two evaluations
+func compareEvaluations(current, proposed *evaluation) []PreviewChange {
+	var names []string
+	seen := make(map[string]bool)
+	for _, eval := range []*evaluation{current, proposed} {
+		for _, name := range eval.ruleNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	var changes []PreviewChange
+	for _, name := range names {
+		before := ruleOutcome(current, name)
+		after := ruleOutcome(proposed, name)
+		if before != after {
+			changes = append(changes, PreviewChange{RuleName: name, Current: before, Proposed: after})
+		}
+	}
+	return changes
+}
+
+// ruleOutcome describes the result of a rule within an evaluation
+func ruleOutcome(eval *evaluation, name string) string {
+	enabled := false
+	for _, ruleName := range eval.ruleNames {
+		if ruleName == name {
+			enabled = true
+			break
+		}
+	}
+	if !enabled {
+		return "Disabled"
+	}
+	if err := eval.ruleErrors[name]; err != nil {
+		return "Error"
+	}
+
+	for _, failure := range eval.failures {
+		if failure.RuleName != name {
+			continue
+		}
+		findings := len(failure.Error)
+		if findings == 0 {
+			findings = 1
+		}
+		return fmt.Sprintf("Failed (%s, %d issue(s))", failure.Severity, findings)
+	}
+
+	return "Passed"
+}
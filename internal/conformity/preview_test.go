@@ -0,0 +1,60 @@
+package conformity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gitlab-mr-conformity-bot/internal/conformity/rules"
+)
+
+func TestChangedConfigFiles(t *testing.T) {
+	changed := changedConfigFiles([]string{"main.go", ".gitlab/CODEOWNERS", "docs/.mr-conform.yaml"})
+	if len(changed) != 1 || changed[0] != ".gitlab/CODEOWNERS" {
+		t.Errorf("expected only CODEOWNERS to be detected, got %v", changed)
+	}
+}
+
+func TestCompareEvaluations(t *testing.T) {
+	current := &evaluation{
+		ruleNames: []string{"Title Validation", "Branch Naming"},
+		failures: []RuleFailure{
+			{RuleName: "Title Validation", Severity: rules.SeverityError, Error: []string{"too long"}},
+		},
+	}
+	proposed := &evaluation{
+		ruleNames: []string{"Title Validation", "Branch Naming", "Squash Commits"},
+		failures: []RuleFailure{
+			{RuleName: "Squash Commits", Severity: rules.SeverityWarning, Error: []string{"squash disabled"}},
+		},
+	}
+
+	changes := compareEvaluations(current, proposed)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	if changes[0].RuleName != "Title Validation" || changes[0].Current != "Failed (error, 1 issue(s))" || changes[0].Proposed != "Passed" {
+		t.Errorf("unexpected title change: %+v", changes[0])
+	}
+	if changes[1].RuleName != "Squash Commits" || changes[1].Current != "Disabled" || !strings.HasPrefix(changes[1].Proposed, "Failed (warning") {
+		t.Errorf("unexpected squash change: %+v", changes[1])
+	}
+
+	summary := NewSummaryGenerator().FormatPreview(&ConfigPreview{Files: []string{".mr-conform.yaml"}, Changes: changes})
+	if !strings.Contains(summary, "| Squash Commits | Disabled |") {
+		t.Errorf("expected preview table in summary, got %q", summary)
+	}
+}
+
+func TestCompareEvaluations_RuleError(t *testing.T) {
+	current := &evaluation{ruleNames: []string{"Approvals"}}
+	proposed := &evaluation{
+		ruleNames:  []string{"Approvals"},
+		ruleErrors: map[string]error{"Approvals": errors.New("CODEOWNERS unavailable")},
+	}
+
+	changes := compareEvaluations(current, proposed)
+	if len(changes) != 1 || changes[0].Current != "Passed" || changes[0].Proposed != "Error" {
+		t.Errorf("expected the rule error to be shown, got %+v", changes)
+	}
+}
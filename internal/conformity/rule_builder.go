@@ -1,6 +1,10 @@
 package conformity
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"gitlab-mr-conformity-bot/internal/config"
 	"gitlab-mr-conformity-bot/internal/conformity/rules"
 )
@@ -17,29 +21,79 @@ func NewRuleBuilder(integrations config.IntegrationsConfig) *RuleBuilder {
 	}
 }
 
-// BuildRules creates rules based on the provided config
-func (rb *RuleBuilder) BuildRules(rulesConfig config.RulesConfig) []rules.Rule {
-	var rulesList []rules.Rule
+// configuredRule is a built rule together with the configuration it was built from
+type configuredRule struct {
+	rule   rules.Rule
+	config interface{}
+}
 
-	// Conditionally initialize rules based on configuration
-	if rulesConfig.Title.Enabled {
-		rulesList = append(rulesList, rules.NewTitleRule(rulesConfig.Title, rb.integrations))
+// BuildRules creates the enabled rules from the registry based on the provided config
+func (rb *RuleBuilder) BuildRules(rulesConfig config.RulesConfig) ([]rules.Rule, error) {
+	configured, err := rb.buildConfiguredRules(rulesConfig)
+	if err != nil {
+		return nil, err
 	}
-	if rulesConfig.Description.Enabled {
-		rulesList = append(rulesList, rules.NewDescriptionRule(rulesConfig.Description, rb.integrations))
+
+	rulesList := make([]rules.Rule, 0, len(configured))
+	for _, cr := range configured {
+		rulesList = append(rulesList, cr.rule)
 	}
-	if rulesConfig.Branch.Enabled {
-		rulesList = append(rulesList, rules.NewBranchRule(rulesConfig.Branch))
+	return rulesList, nil
+}
+
+// buildConfiguredRules creates the enabled rules and keeps the configuration of each
+func (rb *RuleBuilder) buildConfiguredRules(rulesConfig config.RulesConfig) ([]configuredRule, error) {
+	if err := rb.Validate(rulesConfig); err != nil {
+		return nil, err
+	}
+
+	var rulesList []configuredRule
+	severities := rulesConfig.Severities()
+
+	for _, def := range rules.Registered() {
+		cfg, enabled, err := def.Resolve(rulesConfig)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			continue
+		}
+
+		rule := def.Factory(cfg, rb.integrations)
+		if name, ok := severities[def.Key]; ok {
+			severity, err := rules.ParseSeverity(name)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", def.Key, err)
+			}
+			rule = rules.WithSeverity(rule, severity)
+		}
+		rulesList = append(rulesList, configuredRule{rule: rule, config: cfg})
 	}
-	if rulesConfig.Commits.Enabled {
-		rulesList = append(rulesList, rules.NewCommitsRule(rulesConfig.Commits, rb.integrations))
+
+	return rulesList, nil
+}
+
+// Validate reports rule keys in the configuration that no registered rule claims
+// and severities that cannot be parsed
+func (rb *RuleBuilder) Validate(rulesConfig config.RulesConfig) error {
+	for key, name := range rulesConfig.Severities() {
+		if _, err := rules.ParseSeverity(name); err != nil {
+			return fmt.Errorf("rule %q: %w", key, err)
+		}
 	}
-	if rulesConfig.Approvals.Enabled {
-		rulesList = append(rulesList, rules.NewApprovalsRule(rulesConfig.Approvals))
+
+	var unknown []string
+	for key := range rulesConfig.Extra {
+		if _, ok := rules.Lookup(key); !ok {
+			unknown = append(unknown, key)
+		}
 	}
-	if rulesConfig.Squash.Enabled {
-		rulesList = append(rulesList, rules.NewSquashRule(rulesConfig.Squash))
+
+	if len(unknown) == 0 {
+		return nil
 	}
 
-	return rulesList
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown rule(s) in configuration: %s (available rules: %s)",
+		strings.Join(unknown, ", "), strings.Join(rules.RegisteredKeys(), ", "))
 }
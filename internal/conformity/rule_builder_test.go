@@ -0,0 +1,198 @@
+package conformity
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/internal/conformity/helper/codeowners"
+	"gitlab-mr-conformity-bot/internal/conformity/helper/common"
+	"gitlab-mr-conformity-bot/internal/conformity/rules"
+
+	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+)
+
+type labelConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Required []string `mapstructure:"required"`
+	MinCount int      `mapstructure:"min_count"`
+}
+
+type labelRule struct {
+	config labelConfig
+}
+
+func (r *labelRule) Name() string             { return "Labels" }
+func (r *labelRule) Severity() rules.Severity { return rules.SeverityWarning }
+func (r *labelRule) Check(ctx context.Context, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*rules.RuleResult, error) {
+	return &rules.RuleResult{Passed: true}, nil
+}
+
+func init() {
+	rules.Register(rules.Definition{
+		Key: "test_labels",
+		Factory: func(cfg interface{}, integrations config.IntegrationsConfig) rules.Rule {
+			return &labelRule{config: cfg.(labelConfig)}
+		},
+		Defaults: func() interface{} {
+			return &labelConfig{MinCount: 1}
+		},
+	})
+}
+
+func TestBuildRules_BuiltIn(t *testing.T) {
+	rb := NewRuleBuilder(config.IntegrationsConfig{})
+
+	rc := config.RulesConfig{
+		Title:  config.TitleConfig{Enabled: true, MaxLength: 50},
+		Branch: config.BranchConfig{Enabled: true},
+	}
+
+	built, err := rb.BuildRules(rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, r := range built {
+		names[r.Name()] = true
+	}
+	if len(built) != 2 || !names["Title Validation"] || !names["Branch Naming"] {
+		t.Errorf("expected title and branch rules, got %v", names)
+	}
+}
+
+func TestBuildRules_RegisteredByName(t *testing.T) {
+	rb := NewRuleBuilder(config.IntegrationsConfig{})
+
+	rc := config.RulesConfig{
+		Extra: map[string]interface{}{
+			"test_labels": map[string]interface{}{
+				"enabled":  true,
+				"required": []interface{}{"team::backend"},
+			},
+		},
+	}
+
+	built, err := rb.BuildRules(rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(built) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(built))
+	}
+
+	rule, ok := built[0].(*labelRule)
+	if !ok {
+		t.Fatalf("expected *labelRule, got %T", built[0])
+	}
+	if rule.config.MinCount != 1 {
+		t.Errorf("expected default min_count=1 to be kept, got %d", rule.config.MinCount)
+	}
+	if len(rule.config.Required) != 1 || rule.config.Required[0] != "team::backend" {
+		t.Errorf("expected required labels to be decoded, got %v", rule.config.Required)
+	}
+}
+
+func TestBuildRules_RegisteredDisabled(t *testing.T) {
+	rb := NewRuleBuilder(config.IntegrationsConfig{})
+
+	rc := config.RulesConfig{
+		Extra: map[string]interface{}{
+			"test_labels": map[string]interface{}{"enabled": false},
+		},
+	}
+
+	built, err := rb.BuildRules(rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(built) != 0 {
+		t.Errorf("expected no rules, got %d", len(built))
+	}
+}
+
+func TestBuildRules_UnknownKey(t *testing.T) {
+	rb := NewRuleBuilder(config.IntegrationsConfig{})
+
+	rc := config.RulesConfig{
+		Extra: map[string]interface{}{
+			"titel": map[string]interface{}{"enabled": true},
+		},
+	}
+
+	_, err := rb.BuildRules(rc)
+	if err == nil {
+		t.Fatal("expected error for unknown rule key")
+	}
+	if !strings.Contains(err.Error(), `titel`) {
+		t.Errorf("expected error to name the unknown key, got %q", err)
+	}
+}
+
+func TestBuildRules_SeverityOverride(t *testing.T) {
+	rb := NewRuleBuilder(config.IntegrationsConfig{})
+
+	rc := config.RulesConfig{
+		Title:   config.TitleConfig{Enabled: true, Severity: "warning"},
+		Commits: config.CommitsConfig{Enabled: true},
+		Squash:  config.SquashConfig{Enabled: true, Severity: "warn"},
+		Extra: map[string]interface{}{
+			"test_labels": map[string]interface{}{"enabled": true, "severity": "error"},
+		},
+	}
+
+	built, err := rb.BuildRules(rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	severities := map[string]rules.Severity{}
+	for _, r := range built {
+		severities[r.Name()] = r.Severity()
+	}
+
+	expected := map[string]rules.Severity{
+		"Title Validation": rules.SeverityWarning,
+		"Commit Messages":  rules.SeverityWarning,
+		"Squash enforce":   rules.SeverityWarning,
+		"Labels":           rules.SeverityError,
+	}
+	for name, want := range expected {
+		if got := severities[name]; got != want {
+			t.Errorf("%s: expected severity %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestBuildRules_InvalidSeverity(t *testing.T) {
+	rb := NewRuleBuilder(config.IntegrationsConfig{})
+
+	rc := config.RulesConfig{
+		Branch: config.BranchConfig{Enabled: true, Severity: "fatal"},
+	}
+
+	if _, err := rb.BuildRules(rc); err == nil {
+		t.Fatal("expected error for invalid severity")
+	}
+}
+
+func TestSummary_AdvisoryOnly(t *testing.T) {
+	failures := []RuleFailure{
+		{RuleName: "Branch Naming", Severity: rules.SeverityWarning, Error: []string{"bad prefix"}},
+		{RuleName: "Labels", Severity: rules.SeverityInfo, Error: []string{"missing label"}},
+	}
+
+	if countBlocking(failures) != 0 {
+		t.Fatal("expected warnings and info findings to be non-blocking")
+	}
+
+	summary := NewSummaryGenerator().GenerateSummary(failures, nil)
+	if !strings.Contains(summary, "All blocking conformity checks passed") {
+		t.Errorf("expected advisory summary, got %q", summary)
+	}
+	if !strings.Contains(summary, "2 advisory check(s)") {
+		t.Errorf("expected advisory count in summary, got %q", summary)
+	}
+}
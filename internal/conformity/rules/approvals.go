@@ -1,7 +1,9 @@
 package rules
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"gitlab-mr-conformity-bot/internal/config"
 	"gitlab-mr-conformity-bot/internal/conformity/helper/codeowners"
@@ -14,12 +16,33 @@ type ApprovalsRule struct {
 	config config.ApprovalsConfig
 }
 
+func init() {
+	Register(Definition{
+		Key: "approvals",
+		Factory: func(cfg interface{}, integrations config.IntegrationsConfig) Rule {
+			return NewApprovalsRule(cfg)
+		},
+		Defaults: func() interface{} {
+			cfg := defaultApprovalsConfig()
+			return &cfg
+		},
+		Select: func(rc config.RulesConfig) (interface{}, bool) {
+			return rc.Approvals, rc.Approvals.Enabled
+		},
+	})
+}
+
+// defaultApprovalsConfig returns the configuration used when none is provided
+func defaultApprovalsConfig() config.ApprovalsConfig {
+	return config.ApprovalsConfig{
+		MinCount: 1,
+	}
+}
+
 func NewApprovalsRule(cfg interface{}) *ApprovalsRule {
 	approvalsCfg, ok := cfg.(config.ApprovalsConfig)
 	if !ok {
-		approvalsCfg = config.ApprovalsConfig{
-			MinCount: 1,
-		}
+		approvalsCfg = defaultApprovalsConfig()
 	}
 	return &ApprovalsRule{config: approvalsCfg}
 }
@@ -31,7 +54,11 @@ func (r *ApprovalsRule) Name() string {
 func (r *ApprovalsRule) Severity() Severity {
 	return SeverityError
 }
-func (r *ApprovalsRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
+
+func (r *ApprovalsRule) Inputs() Input {
+	return InputApprovals | InputChanges
+}
+func (r *ApprovalsRule) Check(ctx context.Context, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
 	ruleResult := &RuleResult{}
 
 	if !r.config.UseCodeowners {
@@ -58,6 +85,10 @@ func (r *ApprovalsRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.C
 		}
 	}
 
+	if r.config.UseApprovalRules {
+		r.checkApprovalRules(approvals, ruleResult)
+	}
+
 	if len(ruleResult.Error) != 0 {
 		return &RuleResult{
 			Passed:     false,
@@ -68,3 +99,28 @@ func (r *ApprovalsRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.C
 
 	return &RuleResult{Passed: true}, nil
 }
+
+// checkApprovalRules reports GitLab approval rules that are not yet satisfied.
+// Code owner rules are left to the CODEOWNERS check.
+func (r *ApprovalsRule) checkApprovalRules(approvals *common.Approvals, ruleResult *RuleResult) {
+	if approvals == nil || len(approvals.Rules) == 0 {
+		return
+	}
+
+	var pending []string
+	for _, rule := range approvals.Rules {
+		if rule.Approved || rule.ApprovalsRequired == 0 || rule.RuleType == "code_owner" {
+			continue
+		}
+		line := fmt.Sprintf("- %s: %d of %d", rule.Name, len(rule.ApprovedBy), rule.ApprovalsRequired)
+		if len(rule.EligibleApprovers) > 0 {
+			line += " (eligible: @" + strings.Join(rule.EligibleApprovers, ", @") + ")"
+		}
+		pending = append(pending, line)
+	}
+
+	if len(pending) > 0 {
+		ruleResult.Error = append(ruleResult.Error, fmt.Sprintf("%d approval rule(s) not satisfied:\n\n%s", len(pending), strings.Join(pending, "\n")))
+		ruleResult.Suggestion = append(ruleResult.Suggestion, "Request reviews from the eligible approvers of each rule")
+	}
+}
@@ -1,6 +1,7 @@
 package rules
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -15,12 +16,33 @@ type BranchRule struct {
 	config config.BranchConfig
 }
 
+func init() {
+	Register(Definition{
+		Key: "branch",
+		Factory: func(cfg interface{}, integrations config.IntegrationsConfig) Rule {
+			return NewBranchRule(cfg)
+		},
+		Defaults: func() interface{} {
+			cfg := defaultBranchConfig()
+			return &cfg
+		},
+		Select: func(rc config.RulesConfig) (interface{}, bool) {
+			return rc.Branch, rc.Branch.Enabled
+		},
+	})
+}
+
+// defaultBranchConfig returns the configuration used when none is provided
+func defaultBranchConfig() config.BranchConfig {
+	return config.BranchConfig{
+		AllowedPrefixes: []string{"feature/", "bugfix/", "hotfix/"},
+	}
+}
+
 func NewBranchRule(cfg interface{}) *BranchRule {
 	branchCfg, ok := cfg.(config.BranchConfig)
 	if !ok {
-		branchCfg = config.BranchConfig{
-			AllowedPrefixes: []string{"feature/", "bugfix/", "hotfix/"},
-		}
+		branchCfg = defaultBranchConfig()
 	}
 	return &BranchRule{config: branchCfg}
 }
@@ -33,7 +55,11 @@ func (r *BranchRule) Severity() Severity {
 	return SeverityWarning
 }
 
-func (r *BranchRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
+func (r *BranchRule) Inputs() Input {
+	return InputBranches
+}
+
+func (r *BranchRule) Check(ctx context.Context, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
 	ruleResult := &RuleResult{}
 
 	branchName := mr.SourceBranch
@@ -18,19 +18,40 @@ type CommitsRule struct {
 	ticketValidators *ticket.ValidatorManager
 }
 
+func init() {
+	Register(Definition{
+		Key: "commits",
+		Factory: func(cfg interface{}, integrations config.IntegrationsConfig) Rule {
+			return NewCommitsRule(cfg, integrations)
+		},
+		Defaults: func() interface{} {
+			cfg := defaultCommitsConfig()
+			return &cfg
+		},
+		Select: func(rc config.RulesConfig) (interface{}, bool) {
+			return rc.Commits, rc.Commits.Enabled
+		},
+	})
+}
+
+// defaultCommitsConfig returns the configuration used when none is provided
+func defaultCommitsConfig() config.CommitsConfig {
+	return config.CommitsConfig{
+		MaxLength: 72,
+		Conventional: config.ConventionalConfig{
+			Types:  []string{"feat"},
+			Scopes: []string{".*"},
+		},
+		Jira: config.JiraConfig{
+			Keys: []string{""},
+		},
+	}
+}
+
 func NewCommitsRule(cfg interface{}, integrations config.IntegrationsConfig) *CommitsRule {
 	commitsCfg, ok := cfg.(config.CommitsConfig)
 	if !ok {
-		commitsCfg = config.CommitsConfig{
-			MaxLength: 72,
-			Conventional: config.ConventionalConfig{
-				Types:  []string{"feat"},
-				Scopes: []string{".*"},
-			},
-			Jira: config.JiraConfig{
-				Keys: []string{""},
-			},
-		}
+		commitsCfg = defaultCommitsConfig()
 	}
 	return &CommitsRule{
 		config:           commitsCfg,
@@ -46,7 +67,11 @@ func (r *CommitsRule) Severity() Severity {
 	return SeverityWarning
 }
 
-func (r *CommitsRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
+func (r *CommitsRule) Inputs() Input {
+	return InputCommits
+}
+
+func (r *CommitsRule) Check(ctx context.Context, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
 	// Aggregation structures - store commit info instead of just strings
 	var tooLongCommits []*gitlabapi.Commit
 	var invalidFormatCommits []*gitlabapi.Commit
@@ -108,7 +133,6 @@ func (r *CommitsRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Com
 
 		// Ticket validation (Jira, Asana, etc.)
 		if r.ticketValidators.HasValidators() {
-			ctx := context.Background()
 			result := r.ticketValidators.ValidateMessage(ctx, commit.Message)
 
 			if result.AllMissing {
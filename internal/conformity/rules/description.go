@@ -18,13 +18,34 @@ type DescriptionRule struct {
 	ticketValidators *ticket.ValidatorManager
 }
 
+func init() {
+	Register(Definition{
+		Key: "description",
+		Factory: func(cfg interface{}, integrations config.IntegrationsConfig) Rule {
+			return NewDescriptionRule(cfg, integrations)
+		},
+		Defaults: func() interface{} {
+			cfg := defaultDescriptionConfig()
+			return &cfg
+		},
+		Select: func(rc config.RulesConfig) (interface{}, bool) {
+			return rc.Description, rc.Description.Enabled
+		},
+	})
+}
+
+// defaultDescriptionConfig returns the configuration used when none is provided
+func defaultDescriptionConfig() config.DescriptionConfig {
+	return config.DescriptionConfig{
+		Required:  true,
+		MinLength: 20,
+	}
+}
+
 func NewDescriptionRule(cfg interface{}, integrations config.IntegrationsConfig) *DescriptionRule {
 	descCfg, ok := cfg.(config.DescriptionConfig)
 	if !ok {
-		descCfg = config.DescriptionConfig{
-			Required:  true,
-			MinLength: 20,
-		}
+		descCfg = defaultDescriptionConfig()
 	}
 	return &DescriptionRule{
 		config:           descCfg,
@@ -40,7 +61,11 @@ func (r *DescriptionRule) Severity() Severity {
 	return SeverityWarning
 }
 
-func (r *DescriptionRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
+func (r *DescriptionRule) Inputs() Input {
+	return InputDescription
+}
+
+func (r *DescriptionRule) Check(ctx context.Context, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
 	description := strings.TrimSpace(mr.Description)
 	ruleResult := &RuleResult{}
 
@@ -56,7 +81,6 @@ func (r *DescriptionRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi
 
 	// Ticket validation (Jira, Asana, etc.)
 	if r.ticketValidators.HasValidators() && description != "" {
-		ctx := context.Background()
 		result := r.ticketValidators.ValidateMessage(ctx, description)
 
 		if result.AllMissing {
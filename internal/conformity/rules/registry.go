@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"gitlab-mr-conformity-bot/internal/config"
+)
+
+// Factory creates a rule from its configuration
+type Factory func(cfg interface{}, integrations config.IntegrationsConfig) Rule
+
+// Definition describes a rule that can be enabled by name from configuration
+type Definition struct {
+	// Key is the name of the rule under the `rules` configuration section
+	Key string
+	// Factory builds the rule from its configuration
+	Factory Factory
+	// Defaults returns a pointer to a fresh default configuration. Raw
+	// configuration for rules without Select is decoded on top of it.
+	Defaults func() interface{}
+	// Select extracts the typed configuration of a built-in rule and whether it
+	// is enabled. Rules without Select are configured from RulesConfig.Extra.
+	Select func(rc config.RulesConfig) (interface{}, bool)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Definition)
+	order      []string
+)
+
+// Register makes a rule available by its configuration key. It panics if the
+// key is empty, the factory is nil or the key is already registered.
+func Register(def Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if def.Key == "" {
+		panic("rules: Register called with empty key")
+	}
+	if def.Factory == nil {
+		panic("rules: Register called with nil factory for " + def.Key)
+	}
+	if _, dup := registry[def.Key]; dup {
+		panic("rules: Register called twice for " + def.Key)
+	}
+
+	registry[def.Key] = def
+	order = append(order, def.Key)
+}
+
+// Lookup returns the definition registered under key
+func Lookup(key string) (Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	def, ok := registry[key]
+	return def, ok
+}
+
+// Registered returns all rule definitions in registration order
+func Registered() []Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	defs := make([]Definition, 0, len(order))
+	for _, key := range order {
+		defs = append(defs, registry[key])
+	}
+	return defs
+}
+
+// RegisteredKeys returns the sorted configuration keys of all registered rules
+func RegisteredKeys() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	keys := make([]string, len(order))
+	copy(keys, order)
+	sort.Strings(keys)
+	return keys
+}
+
+// ConfigTypes returns the configuration type of every rule configured from
+// RulesConfig.Extra, keyed by rule name
+func ConfigTypes() map[string]reflect.Type {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make(map[string]reflect.Type)
+	for key, def := range registry {
+		if def.Select != nil || def.Defaults == nil {
+			continue
+		}
+		types[key] = reflect.TypeOf(def.Defaults()).Elem()
+	}
+	return types
+}
+
+// Resolve returns the configuration of the rule and whether it is enabled
+func (d Definition) Resolve(rc config.RulesConfig) (interface{}, bool, error) {
+	if d.Select != nil {
+		cfg, enabled := d.Select(rc)
+		return cfg, enabled, nil
+	}
+
+	raw, ok := rc.Extra[d.Key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("rule %q: expected a mapping, got %T", d.Key, raw)
+	}
+
+	enabled, _ := values["enabled"].(bool)
+	if !enabled {
+		return nil, false, nil
+	}
+
+	if d.Defaults == nil {
+		return values, true, nil
+	}
+
+	target := d.Defaults()
+	if err := config.Decode(values, target); err != nil {
+		return nil, false, fmt.Errorf("rule %q: %w", d.Key, err)
+	}
+
+	return reflect.ValueOf(target).Elem().Interface(), true, nil
+}
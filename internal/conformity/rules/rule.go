@@ -1,6 +1,10 @@
 package rules
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"gitlab-mr-conformity-bot/internal/conformity/helper/codeowners"
 	"gitlab-mr-conformity-bot/internal/conformity/helper/common"
 
@@ -10,14 +14,80 @@ import (
 type Severity int
 
 const (
-	SeverityWarning Severity = iota
+	SeverityInfo Severity = iota
+	SeverityWarning
 	SeverityError
 )
 
+// String returns the configuration name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// IsBlocking reports whether failures of this severity fail the merge request
+func (s Severity) IsBlocking() bool {
+	return s >= SeverityError
+}
+
+// ParseSeverity converts a configured severity name into a Severity
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	default:
+		return SeverityInfo, fmt.Errorf("invalid severity %q: must be one of error, warning, info", name)
+	}
+}
+
 type Rule interface {
 	Name() string
 	Severity() Severity
-	Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error)
+	Check(ctx context.Context, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error)
+}
+
+// Input identifies the merge request data a rule reads, so cached results can
+// be reused while that data is unchanged
+type Input uint
+
+const (
+	InputTitle Input = 1 << iota
+	InputDescription
+	InputBranches
+	InputCommits
+	InputApprovals
+	// InputChanges covers the changed files, CODEOWNERS and project members
+	InputChanges
+	// InputSettings covers merge request settings such as squash
+	InputSettings
+
+	// InputAll is assumed for rules that do not declare their inputs
+	InputAll Input = 1<<iota - 1
+)
+
+// InputDeclarer is implemented by rules that declare which data they read
+type InputDeclarer interface {
+	Inputs() Input
+}
+
+// InputsOf returns the inputs of a rule, InputAll when it does not declare them
+func InputsOf(rule Rule) Input {
+	if declarer, ok := rule.(InputDeclarer); ok {
+		return declarer.Inputs()
+	}
+	return InputAll
 }
 
 type RuleResult struct {
@@ -25,3 +95,25 @@ type RuleResult struct {
 	Error      []string
 	Suggestion []string
 }
+
+// severityOverride replaces the built-in severity of a rule with a configured one
+type severityOverride struct {
+	Rule
+	severity Severity
+}
+
+func (r *severityOverride) Severity() Severity {
+	return r.severity
+}
+
+func (r *severityOverride) Inputs() Input {
+	return InputsOf(r.Rule)
+}
+
+// WithSeverity returns the rule reporting the given severity instead of its default
+func WithSeverity(rule Rule, severity Severity) Rule {
+	if rule.Severity() == severity {
+		return rule
+	}
+	return &severityOverride{Rule: rule, severity: severity}
+}
@@ -1,6 +1,7 @@
 package rules
 
 import (
+	"context"
 	"fmt"
 
 	"gitlab-mr-conformity-bot/internal/config"
@@ -16,13 +17,34 @@ type SquashRule struct {
 	config config.SquashConfig
 }
 
+func init() {
+	Register(Definition{
+		Key: "squash",
+		Factory: func(cfg interface{}, integrations config.IntegrationsConfig) Rule {
+			return NewSquashRule(cfg)
+		},
+		Defaults: func() interface{} {
+			cfg := defaultSquashConfig()
+			return &cfg
+		},
+		Select: func(rc config.RulesConfig) (interface{}, bool) {
+			return rc.Squash, rc.Squash.Enabled
+		},
+	})
+}
+
+// defaultSquashConfig returns the configuration used when none is provided
+func defaultSquashConfig() config.SquashConfig {
+	return config.SquashConfig{
+		EnforceBranches:  []string{"feature/*", "fix/*"},
+		DisallowBranches: []string{"release/*"},
+	}
+}
+
 func NewSquashRule(cfg interface{}) *SquashRule {
 	squashCfg, ok := cfg.(config.SquashConfig)
 	if !ok {
-		squashCfg = config.SquashConfig{
-			EnforceBranches:  []string{"feature/*", "fix/*"},
-			DisallowBranches: []string{"release/*"},
-		}
+		squashCfg = defaultSquashConfig()
 	}
 	return &SquashRule{config: squashCfg}
 }
@@ -35,7 +57,11 @@ func (r *SquashRule) Severity() Severity {
 	return SeverityError
 }
 
-func (r *SquashRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
+func (r *SquashRule) Inputs() Input {
+	return InputBranches | InputSettings
+}
+
+func (r *SquashRule) Check(ctx context.Context, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
 	branchName := mr.SourceBranch
 	matched := false
 	ruleResult := &RuleResult{}
@@ -19,20 +19,41 @@ type TitleRule struct {
 	ticketValidators *ticket.ValidatorManager
 }
 
+func init() {
+	Register(Definition{
+		Key: "title",
+		Factory: func(cfg interface{}, integrations config.IntegrationsConfig) Rule {
+			return NewTitleRule(cfg, integrations)
+		},
+		Defaults: func() interface{} {
+			cfg := defaultTitleConfig()
+			return &cfg
+		},
+		Select: func(rc config.RulesConfig) (interface{}, bool) {
+			return rc.Title, rc.Title.Enabled
+		},
+	})
+}
+
+// defaultTitleConfig returns the configuration used when none is provided
+func defaultTitleConfig() config.TitleConfig {
+	return config.TitleConfig{
+		MinLength: 10,
+		MaxLength: 100,
+		Conventional: config.ConventionalConfig{
+			Types:  []string{"feat"},
+			Scopes: []string{".*"},
+		},
+		Jira: config.JiraConfig{
+			Keys: []string{""},
+		},
+	}
+}
+
 func NewTitleRule(cfg interface{}, integrations config.IntegrationsConfig) *TitleRule {
 	titleCfg, ok := cfg.(config.TitleConfig)
 	if !ok {
-		titleCfg = config.TitleConfig{
-			MinLength: 10,
-			MaxLength: 100,
-			Conventional: config.ConventionalConfig{
-				Types:  []string{"feat"},
-				Scopes: []string{".*"},
-			},
-			Jira: config.JiraConfig{
-				Keys: []string{""},
-			},
-		}
+		titleCfg = defaultTitleConfig()
 	}
 	return &TitleRule{
 		config:           titleCfg,
@@ -48,7 +69,11 @@ func (r *TitleRule) Severity() Severity {
 	return SeverityError
 }
 
-func (r *TitleRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
+func (r *TitleRule) Inputs() Input {
+	return InputTitle
+}
+
+func (r *TitleRule) Check(ctx context.Context, mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commit, approvals *common.Approvals, cos []*codeowners.PatternGroup, members []*gitlabapi.ProjectMember) (*RuleResult, error) {
 	ruleResult := &RuleResult{}
 
 	title := mr.Title
@@ -116,7 +141,6 @@ func (r *TitleRule) Check(mr *gitlabapi.MergeRequest, commits []*gitlabapi.Commi
 
 	// Ticket validation (Jira, Asana, etc.)
 	if r.ticketValidators.HasValidators() {
-		ctx := context.Background()
 		result := r.ticketValidators.ValidateMessage(ctx, title)
 
 		if result.AllMissing {
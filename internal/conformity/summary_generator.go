@@ -15,13 +15,71 @@ func NewSummaryGenerator() *SummaryGenerator {
 	return &SummaryGenerator{}
 }
 
-// GenerateSummary creates a formatted summary from rule failures
-func (sg *SummaryGenerator) GenerateSummary(failures []RuleFailure) string {
+// GenerateSummary creates a formatted summary from rule failures and warnings
+// about configuration files that were ignored
+func (sg *SummaryGenerator) GenerateSummary(failures []RuleFailure, configWarnings []string) string {
+	var summary string
 	if len(failures) == 0 {
-		return sg.generateSuccessSummary()
+		summary = sg.generateSuccessSummary()
+	} else {
+		summary = sg.generateFailureSummary(failures)
 	}
 
-	return sg.generateFailureSummary(failures)
+	if len(configWarnings) > 0 {
+		summary += sg.formatConfigWarnings(configWarnings)
+	}
+
+	return summary
+}
+
+// formatConfigWarnings formats warnings about invalid configuration files
+func (sg *SummaryGenerator) formatConfigWarnings(warnings []string) string {
+	summary := "\n\n#### ⚠️ **Configuration warnings**\n\n"
+	for _, warning := range warnings {
+		summary += fmt.Sprintf("- %s\n", warning)
+	}
+	summary += "\n>💡 **Tip**: Run `validate-config` against `.mr-conform.yaml` to see all problems. Inherited configuration is used until the file is fixed.\n"
+	return summary
+}
+
+// FormatCodeownersSource states which CODEOWNERS file and ref approvals were checked against
+func (sg *SummaryGenerator) FormatCodeownersSource(source *CodeownersSource) string {
+	return fmt.Sprintf("\n\n<sub>📁 Code owners from `%s` on `%s`</sub>\n", source.Path, source.Ref)
+}
+
+// FormatPreview formats the results that would change with the configuration proposed by a merge request
+func (sg *SummaryGenerator) FormatPreview(preview *ConfigPreview) string {
+	summary := "\n\n---\n\n### 🔍 Config preview\n\n"
+	summary += fmt.Sprintf("This merge request changes %s. The status above is based on the configuration of the default branch; once merged, results would be:\n\n", sg.formatFiles(preview.Files))
+
+	if len(preview.Changes) == 0 {
+		summary += "No results would change.\n"
+	} else {
+		summary += "| Rule | Current | With proposed config |\n| --- | --- | --- |\n"
+		for _, change := range preview.Changes {
+			summary += fmt.Sprintf("| %s | %s | %s |\n", change.RuleName, change.Current, change.Proposed)
+		}
+	}
+
+	if len(preview.Warnings) > 0 {
+		summary += "\n> **🚨 Proposed configuration problems:**\n"
+		for _, warning := range preview.Warnings {
+			summary += fmt.Sprintf("> - %s\n", warning)
+		}
+	}
+
+	return summary
+}
+
+func (sg *SummaryGenerator) formatFiles(files []string) string {
+	var out string
+	for i, file := range files {
+		if i > 0 {
+			out += " and "
+		}
+		out += fmt.Sprintf("`%s`", file)
+	}
+	return out
 }
 
 // generateSuccessSummary creates a summary for when all checks pass
@@ -29,9 +87,21 @@ func (sg *SummaryGenerator) generateSuccessSummary() string {
 	return "## 🧾 **Merge Request Compliance Report**\n\n✅ **All conformity checks passed!**"
 }
 
-// generateFailureSummary creates a summary for when checks fail
+// generateFailureSummary creates a summary for when checks fail or report advisory findings
 func (sg *SummaryGenerator) generateFailureSummary(failures []RuleFailure) string {
-	summary := fmt.Sprintf("## 🧾 **Merge Request Compliance Report**\n\n### ❌ %d conformity check(s) failed:\n\n---\n\n", len(failures))
+	blocking := countBlocking(failures)
+	advisory := len(failures) - blocking
+
+	summary := "## 🧾 **Merge Request Compliance Report**\n\n"
+	if blocking > 0 {
+		summary += fmt.Sprintf("### ❌ %d conformity check(s) failed:\n\n", blocking)
+	} else {
+		summary += "✅ **All blocking conformity checks passed!**\n\n"
+	}
+	if advisory > 0 {
+		summary += fmt.Sprintf("### ⚠️ %d advisory check(s) reported findings (non-blocking):\n\n", advisory)
+	}
+	summary += "---\n\n"
 
 	// Sort failures by severity (higher severity first)
 	sortedFailures := sg.sortFailuresBySeverity(failures)
@@ -48,7 +118,7 @@ func (sg *SummaryGenerator) sortFailuresBySeverity(failures []RuleFailure) []Rul
 	sortedFailures := make([]RuleFailure, len(failures))
 	copy(sortedFailures, failures)
 
-	sort.Slice(sortedFailures, func(i, j int) bool {
+	sort.SliceStable(sortedFailures, func(i, j int) bool {
 		return sortedFailures[i].Severity > sortedFailures[j].Severity
 	})
 
@@ -75,8 +145,12 @@ func (sg *SummaryGenerator) formatFailure(failure RuleFailure) string {
 
 // getSeverityEmoji returns the appropriate emoji for a given severity
 func (sg *SummaryGenerator) getSeverityEmoji(severity rules.Severity) string {
-	if severity == rules.SeverityError {
+	switch severity {
+	case rules.SeverityError:
 		return "❌"
+	case rules.SeverityInfo:
+		return "ℹ️"
+	default:
+		return "⚠️"
 	}
-	return "⚠️"
 }
@@ -1,7 +1,9 @@
 package gitlab
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"gitlab-mr-conformity-bot/internal/conformity/helper/common"
 	"net/http"
@@ -15,48 +17,137 @@ type Client struct {
 	client *gitlab.Client
 }
 
-func NewClient(token, baseURL string, insecure bool) (*Client, error) {
-	var client *gitlab.Client
-	var err error
-
+// NewClient creates a GitLab API client. Timeout bounds every single API
+// request; cancellation of a whole operation is driven by the caller's context.
+// Requests are throttled and retried as configured by limits.
+func NewClient(token, baseURL string, insecure bool, timeout time.Duration, limits RateLimitConfig) (*Client, error) {
+	var transport http.RoundTripper
 	if insecure {
-		// Create a custom HTTP client that skips TLS verification
-		httpClient := &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true, // ⚠️ Use with caution!
-				},
+		// Skip TLS verification
+		transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true, // ⚠️ Use with caution!
 			},
 		}
+	}
 
-		client, err = gitlab.NewClient(token,
-			gitlab.WithBaseURL(baseURL),
-			gitlab.WithHTTPClient(httpClient),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create GitLab client (insecure): %w", err)
-		}
-	} else {
-		client, err = gitlab.NewClient(token, gitlab.WithBaseURL(baseURL))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create GitLab client: %w", err)
-		}
+	limiter := newRateLimiter(limits.RequestsPerSecond)
+	httpClient := &http.Client{
+		Timeout:   timeout,
+		Transport: newThrottledTransport(transport, limiter, limits.MaxConcurrency),
+	}
+
+	client, err := gitlab.NewClient(token,
+		gitlab.WithBaseURL(baseURL),
+		gitlab.WithHTTPClient(httpClient),
+		gitlab.WithCustomLimiter(limiter),
+		gitlab.WithCustomRetry(checkRetry),
+		gitlab.WithCustomBackoff(backoff),
+		gitlab.WithCustomRetryMax(limits.MaxRetries),
+		gitlab.WithCustomRetryWaitMinMax(limits.RetryWaitMin, limits.RetryWaitMax),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
 	}
 
 	return &Client{client: client}, nil
 }
 
-func (c *Client) GetMergeRequest(projectID interface{}, mrID int) (*gitlab.MergeRequest, error) {
-	mr, _, err := c.client.MergeRequests.GetMergeRequest(projectID, mrID, nil)
+func (c *Client) GetMergeRequest(ctx context.Context, projectID interface{}, mrID int) (*gitlab.MergeRequest, error) {
+	mr, _, err := c.client.MergeRequests.GetMergeRequest(projectID, mrID, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get merge request: %w", err)
 	}
 	return mr, nil
 }
 
-func (c *Client) ListMergeRequestApprovals(projectID interface{}, mrID int, creatorID int, excludeCreator bool) (*common.Approvals, error) {
+// ListMergeRequestApprovals returns the approvals of a merge request. Approvals
+// and approval rules are read from the approvals API; when it is unavailable
+// approval state is reconstructed from system notes instead.
+func (c *Client) ListMergeRequestApprovals(ctx context.Context, projectID interface{}, mrID int, creatorID int, excludeCreator bool) (*common.Approvals, error) {
+	approvals, err := c.listApprovalsFromAPI(ctx, projectID, mrID, creatorID, excludeCreator)
+	if err == nil {
+		return approvals, nil
+	}
+
+	approvals, notesErr := c.listApprovalsFromNotes(ctx, projectID, mrID, creatorID, excludeCreator)
+	if notesErr != nil {
+		return nil, fmt.Errorf("failed to get approvals: %w (fallback: %v)", err, notesErr)
+	}
+	return approvals, nil
+}
+
+// listApprovalsFromAPI reads approvals from the merge request approvals endpoint
+// and, where available (GitLab Premium), approval rules from the approval state
+func (c *Client) listApprovalsFromAPI(ctx context.Context, projectID interface{}, mrID int, creatorID int, excludeCreator bool) (*common.Approvals, error) {
+	configuration, _, err := c.client.MergeRequestApprovals.GetConfiguration(projectID, mrID, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request approvals: %w", err)
+	}
+
+	userApprovals := make(map[int]common.ApprovalInfo)
+	count := 0
+	for _, approver := range configuration.ApprovedBy {
+		if approver == nil || approver.User == nil {
+			continue
+		}
+		userApprovals[approver.User.ID] = common.ApprovalInfo{
+			UserID:   approver.User.ID,
+			Username: approver.User.Username,
+			Status:   "approved",
+		}
+		// Exclude creator from count if option is enabled
+		if excludeCreator && approver.User.ID == creatorID {
+			continue
+		}
+		count++
+	}
+
+	approvals := &common.Approvals{
+		ApprovalsCount: count,
+		ApprovalsInfo:  userApprovals,
+		Source:         common.ApprovalSourceAPI,
+	}
+
+	// Approval rules are a paid feature, their absence is not an error
+	state, _, err := c.client.MergeRequestApprovals.GetApprovalState(projectID, mrID, gitlab.WithContext(ctx))
+	if err == nil && state != nil {
+		for _, rule := range state.Rules {
+			if rule != nil {
+				approvals.Rules = append(approvals.Rules, convertApprovalRule(rule))
+			}
+		}
+	}
+
+	return approvals, nil
+}
+
+func convertApprovalRule(rule *gitlab.MergeRequestApprovalRule) common.ApprovalRule {
+	converted := common.ApprovalRule{
+		ID:                rule.ID,
+		Name:              rule.Name,
+		RuleType:          rule.RuleType,
+		Section:           rule.Section,
+		ApprovalsRequired: rule.ApprovalsRequired,
+		Approved:          rule.Approved,
+	}
+	for _, user := range rule.EligibleApprovers {
+		if user != nil {
+			converted.EligibleApprovers = append(converted.EligibleApprovers, user.Username)
+		}
+	}
+	for _, user := range rule.ApprovedBy {
+		if user != nil {
+			converted.ApprovedBy = append(converted.ApprovedBy, user.Username)
+		}
+	}
+	return converted
+}
+
+// listApprovalsFromNotes reconstructs approval state from system notes
+func (c *Client) listApprovalsFromNotes(ctx context.Context, projectID interface{}, mrID int, creatorID int, excludeCreator bool) (*common.Approvals, error) {
 	// List notes
-	notes, err := c.getAllNotes(projectID, mrID)
+	notes, err := c.getAllNotes(ctx, projectID, mrID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list notes: %w", err)
 	}
@@ -109,24 +200,25 @@ func (c *Client) ListMergeRequestApprovals(projectID interface{}, mrID int, crea
 	approvals := common.Approvals{
 		ApprovalsCount: count,
 		ApprovalsInfo:  userApprovals,
+		Source:         common.ApprovalSourceNotes,
 	}
 
 	return &approvals, nil
 }
 
-func (c *Client) ListMergeRequestCommits(projectID interface{}, mrID int) ([]*gitlab.Commit, error) {
-	commits, _, err := c.client.MergeRequests.GetMergeRequestCommits(projectID, mrID, nil)
+func (c *Client) ListMergeRequestCommits(ctx context.Context, projectID interface{}, mrID int) ([]*gitlab.Commit, error) {
+	commits, _, err := c.client.MergeRequests.GetMergeRequestCommits(projectID, mrID, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get merge request commits: %w", err)
 	}
 	return commits, nil
 }
 
-func (c *Client) CreateUpdateMergeRequestDiscussion(projectID interface{}, mrID int, note string, passed bool) error {
+func (c *Client) CreateUpdateMergeRequestDiscussion(ctx context.Context, projectID interface{}, mrID int, note string, passed bool) error {
 	identifier := "Merge Request Compliance Report"
 
 	// List discussions
-	discussions, err := c.getAllDiscussions(projectID, mrID)
+	discussions, err := c.getAllDiscussions(ctx, projectID, mrID)
 	if err != nil {
 		return fmt.Errorf("failed to list discussions: %w", err)
 	}
@@ -140,14 +232,14 @@ func (c *Client) CreateUpdateMergeRequestDiscussion(projectID interface{}, mrID
 				// Update the existing note
 				_, _, err := c.client.Notes.UpdateMergeRequestNote(projectID, mrID, n.ID, &gitlab.UpdateMergeRequestNoteOptions{
 					Body: &note,
-				})
+				}, gitlab.WithContext(ctx))
 				if err != nil {
 					return fmt.Errorf("failed to update discussion: %w", err)
 				}
 				if n.Resolved != passed {
 					_, _, err = c.client.Discussions.ResolveMergeRequestDiscussion(projectID, mrID, d.ID, &gitlab.ResolveMergeRequestDiscussionOptions{
 						Resolved: &passed,
-					})
+					}, gitlab.WithContext(ctx))
 					if err != nil {
 						return fmt.Errorf("failed to resolve discussion: %w", err)
 					}
@@ -163,7 +255,7 @@ func (c *Client) CreateUpdateMergeRequestDiscussion(projectID interface{}, mrID
 	}
 
 	// Create discussion
-	cD, _, err := c.client.Discussions.CreateMergeRequestDiscussion(projectID, mrID, cdOpts)
+	cD, _, err := c.client.Discussions.CreateMergeRequestDiscussion(projectID, mrID, cdOpts, gitlab.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create merge request discussion: %w", err)
 	}
@@ -172,7 +264,7 @@ func (c *Client) CreateUpdateMergeRequestDiscussion(projectID interface{}, mrID
 	// Set resolve status
 	_, _, err = c.client.Discussions.ResolveMergeRequestDiscussion(projectID, mrID, cD.ID, &gitlab.ResolveMergeRequestDiscussionOptions{
 		Resolved: &passed,
-	})
+	}, gitlab.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to set resolve status: %w", err)
 	}
@@ -180,54 +272,76 @@ func (c *Client) CreateUpdateMergeRequestDiscussion(projectID interface{}, mrID
 	return nil
 }
 
-func (c *Client) CreateMergeRequestNote(projectID interface{}, mrID int, note string) error {
+func (c *Client) CreateMergeRequestNote(ctx context.Context, projectID interface{}, mrID int, note string) error {
 	opts := &gitlab.CreateMergeRequestNoteOptions{
 		Body: &note,
 	}
-	_, _, err := c.client.Notes.CreateMergeRequestNote(projectID, mrID, opts)
+	_, _, err := c.client.Notes.CreateMergeRequestNote(projectID, mrID, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create merge request note: %w", err)
 	}
 	return nil
 }
 
-func (c *Client) SetCommitStatus(projectID interface{}, sha, state, description string) error {
+func (c *Client) SetCommitStatus(ctx context.Context, projectID interface{}, sha, state, description string) error {
 	opts := &gitlab.SetCommitStatusOptions{
 		State:       gitlab.BuildStateValue(state),
 		Description: &description,
 		Name:        gitlab.Ptr("MR Conform"),
 	}
 
-	_, _, err := c.client.Commits.SetCommitStatus(projectID, sha, opts)
+	_, _, err := c.client.Commits.SetCommitStatus(projectID, sha, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to set commit status: %w", err)
 	}
 	return nil
 }
 
-func (c *Client) GetConfigFile(projectID interface{}) (*gitlab.File, error) {
-	// Check default branch
-	cP, _, err := c.client.Projects.GetProject(projectID, nil)
+func (c *Client) GetProject(ctx context.Context, projectID interface{}) (*gitlab.Project, error) {
+	project, _, err := c.client.Projects.GetProject(projectID, nil, gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get repository info: %w", err)
+		return nil, fmt.Errorf("failed to get project: %w", err)
 	}
-	cfg, _, err := c.client.RepositoryFiles.GetFile(projectID, ".mr-conform.yaml", &gitlab.GetFileOptions{
-		Ref: &cP.DefaultBranch,
-	})
+	return project, nil
+}
+
+// GetConfigFile returns .mr-conform.yaml at ref, or on the default branch when ref is empty
+func (c *Client) GetConfigFile(ctx context.Context, projectID interface{}, ref string) (*gitlab.File, error) {
+	cfg, err := c.GetFile(ctx, projectID, ".mr-conform.yaml", ref)
 	if err != nil {
 		return nil, fmt.Errorf("failed to config file: %w", err)
 	}
 	return cfg, nil
 }
 
-func (c *Client) getAllDiscussions(projectID interface{}, mrID int) ([]*gitlab.Discussion, error) {
+// GetFile returns a repository file at ref, or on the default branch when ref is empty
+func (c *Client) GetFile(ctx context.Context, projectID interface{}, path, ref string) (*gitlab.File, error) {
+	if ref == "" {
+		// Check default branch
+		cP, _, err := c.client.Projects.GetProject(projectID, nil, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get repository info: %w", err)
+		}
+		ref = cP.DefaultBranch
+	}
+
+	file, _, err := c.client.RepositoryFiles.GetFile(projectID, path, &gitlab.GetFileOptions{
+		Ref: &ref,
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+func (c *Client) getAllDiscussions(ctx context.Context, projectID interface{}, mrID int) ([]*gitlab.Discussion, error) {
 	var allDiscussions []*gitlab.Discussion
 	opt := &gitlab.ListMergeRequestDiscussionsOptions{
 		PerPage: 100,
 	}
 
 	for {
-		discussions, resp, err := c.client.Discussions.ListMergeRequestDiscussions(projectID, mrID, opt)
+		discussions, resp, err := c.client.Discussions.ListMergeRequestDiscussions(projectID, mrID, opt, gitlab.WithContext(ctx))
 		if err != nil {
 			return nil, fmt.Errorf("failed to list discussions: %w", err)
 		}
@@ -243,12 +357,12 @@ func (c *Client) getAllDiscussions(projectID interface{}, mrID int) ([]*gitlab.D
 	return allDiscussions, nil
 }
 
-func (c *Client) getAllNotes(projectID interface{}, mrID int) ([]*gitlab.Note, error) {
+func (c *Client) getAllNotes(ctx context.Context, projectID interface{}, mrID int) ([]*gitlab.Note, error) {
 	var allNotes []*gitlab.Note
 	opt := &gitlab.ListMergeRequestNotesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
 
 	for {
-		notes, resp, err := c.client.Notes.ListMergeRequestNotes(projectID, mrID, opt)
+		notes, resp, err := c.client.Notes.ListMergeRequestNotes(projectID, mrID, opt, gitlab.WithContext(ctx))
 		if err != nil {
 			return nil, fmt.Errorf("failed to list notes: %w", err)
 		}
@@ -264,12 +378,12 @@ func (c *Client) getAllNotes(projectID interface{}, mrID int) ([]*gitlab.Note, e
 	return allNotes, nil
 }
 
-func (c *Client) GetAllDiffsPaths(projectID interface{}, mrID int) ([]string, error) {
+func (c *Client) GetAllDiffsPaths(ctx context.Context, projectID interface{}, mrID int) ([]string, error) {
 	var allDiffs []*gitlab.MergeRequestDiff
-	opt := &gitlab.ListMergeRequestDiffsOptions{ListOptions: gitlab.ListOptions{PerPage: 20}}
+	opt := &gitlab.ListMergeRequestDiffsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
 
 	for {
-		diffs, resp, err := c.client.MergeRequests.ListMergeRequestDiffs(projectID, mrID, opt)
+		diffs, resp, err := c.client.MergeRequests.ListMergeRequestDiffs(projectID, mrID, opt, gitlab.WithContext(ctx))
 		if err != nil {
 			return nil, fmt.Errorf("failed to list diffs: %w", err)
 		}
@@ -295,27 +409,44 @@ func (c *Client) GetAllDiffsPaths(projectID interface{}, mrID int) ([]string, er
 	return allPaths, nil
 }
 
-func (c *Client) GetCodeownersFile(projectID interface{}) (*gitlab.File, error) {
-	// Check default branch
-	cP, _, err := c.client.Projects.GetProject(projectID, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get repository info: %w", err)
+// CodeownersPaths lists the locations of the CODEOWNERS file in the order
+// GitLab looks them up; the first file found is used
+var CodeownersPaths = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// GetCodeownersFile returns the CODEOWNERS file GitLab would use at ref, or on
+// the default branch when ref is empty. The returned file records its path and ref.
+func (c *Client) GetCodeownersFile(ctx context.Context, projectID interface{}, ref string) (*gitlab.File, error) {
+	if ref == "" {
+		cP, _, err := c.client.Projects.GetProject(projectID, nil, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get repository info: %w", err)
+		}
+		ref = cP.DefaultBranch
 	}
-	co, _, err := c.client.RepositoryFiles.GetFile(projectID, ".gitlab/CODEOWNERS", &gitlab.GetFileOptions{
-		Ref: &cP.DefaultBranch,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to CODEOWNERS file: %w", err)
+
+	for _, path := range CodeownersPaths {
+		co, err := c.GetFile(ctx, projectID, path, ref)
+		if errors.Is(err, gitlab.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CODEOWNERS file: %w", err)
+		}
+		if co.Ref == "" {
+			co.Ref = ref
+		}
+		return co, nil
 	}
 
-	return co, nil
+	return nil, fmt.Errorf("no CODEOWNERS file found at %s in %s", ref, strings.Join(CodeownersPaths, ", "))
 }
-func (c *Client) ListProjectMembers(projectID interface{}) ([]*gitlab.ProjectMember, error) {
+
+func (c *Client) ListProjectMembers(ctx context.Context, projectID interface{}) ([]*gitlab.ProjectMember, error) {
 	var allMembers []*gitlab.ProjectMember
-	opt := &gitlab.ListProjectMembersOptions{ListOptions: gitlab.ListOptions{PerPage: 20}}
+	opt := &gitlab.ListProjectMembersOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
 
 	for {
-		members, resp, err := c.client.ProjectMembers.ListAllProjectMembers(projectID, opt)
+		members, resp, err := c.client.ProjectMembers.ListAllProjectMembers(projectID, opt, gitlab.WithContext(ctx))
 		if err != nil {
 			return nil, fmt.Errorf("failed to list project members: %w", err)
 		}
@@ -339,3 +470,54 @@ func (c *Client) ListProjectMembers(projectID interface{}) ([]*gitlab.ProjectMem
 
 	return activeMembers, nil
 }
+
+// ListGroupMemberUsernames returns the usernames of the active members of a
+// group, including inherited members and the members of its subgroups.
+// A group that does not exist returns an error wrapping gitlab.ErrNotFound.
+func (c *Client) ListGroupMemberUsernames(ctx context.Context, group string) ([]string, error) {
+	groups := []string{group}
+
+	descOpt := &gitlab.ListDescendantGroupsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for {
+		descendants, resp, err := c.client.Groups.ListDescendantGroups(group, descOpt, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list subgroups of %s: %w", group, err)
+		}
+		for _, descendant := range descendants {
+			groups = append(groups, descendant.FullPath)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		descOpt.Page = resp.NextPage
+	}
+
+	seen := make(map[string]bool)
+	var usernames []string
+	now := time.Now()
+
+	for _, g := range groups {
+		opt := &gitlab.ListGroupMembersOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+		for {
+			members, resp, err := c.client.Groups.ListAllGroupMembers(g, opt, gitlab.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("failed to list members of %s: %w", g, err)
+			}
+			for _, member := range members {
+				if member.State != "active" || (member.ExpiresAt != nil && !time.Time(*member.ExpiresAt).After(now)) {
+					continue
+				}
+				if !seen[member.Username] {
+					seen[member.Username] = true
+					usernames = append(usernames, member.Username)
+				}
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opt.Page = resp.NextPage
+		}
+	}
+
+	return usernames, nil
+}
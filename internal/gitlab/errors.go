@@ -0,0 +1,29 @@
+package gitlab
+
+import (
+	"errors"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// permanentStatuses are responses that do not change when a request is repeated
+var permanentStatuses = map[int]bool{
+	http.StatusBadRequest:          true,
+	http.StatusUnauthorized:        true,
+	http.StatusForbidden:           true,
+	http.StatusNotFound:            true,
+	http.StatusMethodNotAllowed:    true,
+	http.StatusGone:                true,
+	http.StatusUnprocessableEntity: true,
+}
+
+// IsPermanentStatus reports whether err was caused by a GitLab response that a
+// retry would get again, such as a missing merge request or a lack of access
+func IsPermanentStatus(err error) bool {
+	var errResp *gitlab.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	return permanentStatuses[errResp.Response.StatusCode]
+}
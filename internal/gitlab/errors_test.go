@@ -0,0 +1,33 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestIsPermanent(t *testing.T) {
+	response := func(status int) error {
+		return fmt.Errorf("failed to get merge request: %w", &gitlab.ErrorResponse{Response: &http.Response{StatusCode: status}})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"not found", response(http.StatusNotFound), true},
+		{"forbidden", response(http.StatusForbidden), true},
+		{"rate limited", response(http.StatusTooManyRequests), false},
+		{"server error", response(http.StatusBadGateway), false},
+		{"network error", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := IsPermanentStatus(tt.err); got != tt.want {
+			t.Errorf("%s: IsPermanentStatus = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
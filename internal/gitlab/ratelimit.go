@@ -0,0 +1,236 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/metrics"
+	"gitlab-mr-conformity-bot/internal/tracing"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitConfig controls retries, throttling and concurrency of GitLab API requests
+type RateLimitConfig struct {
+	// MaxRetries is the number of retries of a failed request
+	MaxRetries int
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff between retries
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	// RequestsPerSecond caps the request rate, 0 leaves it to the RateLimit-* headers
+	RequestsPerSecond float64
+	// MaxConcurrency caps the number of requests in flight, 0 means unlimited
+	MaxConcurrency int
+}
+
+// rateLimitReserve is the share of the rate limit window kept in reserve; once
+// fewer requests remain, new requests wait for the window to reset
+const rateLimitReserve = 0.1
+
+// idempotentMethods are retried on server and network errors
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+}
+
+// rateLimiter throttles requests to a fixed rate and pauses all requests when
+// GitLab reports that the rate limit is (nearly) exhausted
+type rateLimiter struct {
+	limiter *rate.Limiter
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	limit := rate.Inf
+	if requestsPerSecond > 0 {
+		limit = rate.Limit(requestsPerSecond)
+	}
+	burst := int(math.Max(1, requestsPerSecond))
+	return &rateLimiter{limiter: rate.NewLimiter(limit, burst)}
+}
+
+// Wait blocks until a request may be sent, implementing gitlab.RateLimiter
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	wait := time.Until(l.pausedUntil)
+	l.mu.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return l.limiter.Wait(ctx)
+}
+
+// pauseUntil holds back all requests until t
+func (l *rateLimiter) pauseUntil(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t.After(l.pausedUntil) {
+		l.pausedUntil = t
+	}
+}
+
+// observe pauses requests when a response shows the rate limit is exhausted
+func (l *rateLimiter) observe(resp *http.Response) {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if wait, ok := retryAfter(resp); ok {
+			l.pauseUntil(time.Now().Add(wait))
+		}
+		return
+	}
+
+	limit, errLimit := strconv.Atoi(resp.Header.Get("RateLimit-Limit"))
+	remaining, errRemaining := strconv.Atoi(resp.Header.Get("RateLimit-Remaining"))
+	if errLimit != nil || errRemaining != nil || limit <= 0 {
+		return
+	}
+	if float64(remaining) > float64(limit)*rateLimitReserve {
+		return
+	}
+	if reset, ok := rateLimitReset(resp); ok {
+		l.pauseUntil(reset)
+	}
+}
+
+// throttledTransport limits concurrent requests, feeds responses to the rate
+// limiter and records request metrics and spans
+type throttledTransport struct {
+	next    http.RoundTripper
+	limiter *rateLimiter
+	slots   chan struct{}
+}
+
+func newThrottledTransport(next http.RoundTripper, limiter *rateLimiter, maxConcurrency int) *throttledTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &throttledTransport{next: next, limiter: limiter}
+	if maxConcurrency > 0 {
+		t.slots = make(chan struct{}, maxConcurrency)
+	}
+	return t
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+			defer func() { <-t.slots }()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	ctx, span := tracing.Start(req.Context(), "gitlab.request", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.path", req.URL.Path),
+	))
+	defer span.End()
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	metrics.ObserveGitLabRequest(req.Method, resp, err, start)
+	tracing.RecordError(span, err)
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	if err == nil {
+		t.limiter.observe(resp)
+	}
+	return resp, err
+}
+
+// checkRetry retries rate limited requests, and server and network errors of
+// idempotent requests. Other requests may already have taken effect.
+func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && idempotentMethods[strings.ToUpper(urlErr.Op)] {
+			return true, nil
+		}
+		return false, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, nil
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return resp.Request != nil && idempotentMethods[resp.Request.Method], nil
+	}
+
+	return false, nil
+}
+
+// backoff waits as long as GitLab asks for with Retry-After or RateLimit-Reset,
+// and otherwise grows exponentially with jitter, bounded by max
+func backoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if wait, ok := retryAfter(resp); ok {
+			return wait
+		}
+		if reset, ok := rateLimitReset(resp); ok {
+			if wait := time.Until(reset); wait > 0 {
+				return wait
+			}
+		}
+	}
+
+	wait := time.Duration(float64(min) * math.Pow(2, float64(attemptNum)))
+	if wait <= 0 || wait > max {
+		wait = max
+	}
+	// Up to 20% jitter avoids retrying in lockstep with other workers
+	return wait - time.Duration(rand.Float64()*0.2*float64(wait))
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+	return 0, false
+}
+
+// rateLimitReset parses the RateLimit-Reset header, a Unix timestamp
+func rateLimitReset(resp *http.Response) (time.Time, bool) {
+	reset, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64)
+	if err != nil || reset <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(reset, 0), true
+}
+
+var _ retryablehttp.CheckRetry = checkRetry
+var _ retryablehttp.Backoff = backoff
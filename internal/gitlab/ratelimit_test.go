@@ -0,0 +1,66 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCheckRetry(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "https://gitlab.example.com/api/v4/projects", nil)
+	post, _ := http.NewRequest(http.MethodPost, "https://gitlab.example.com/api/v4/projects", nil)
+
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"rate limited write", &http.Response{StatusCode: http.StatusTooManyRequests, Request: post}, nil, true},
+		{"server error on read", &http.Response{StatusCode: http.StatusBadGateway, Request: get}, nil, true},
+		{"server error on write", &http.Response{StatusCode: http.StatusBadGateway, Request: post}, nil, false},
+		{"client error", &http.Response{StatusCode: http.StatusNotFound, Request: get}, nil, false},
+		{"network error on read", nil, &url.Error{Op: "Get", Err: errors.New("connection reset")}, true},
+		{"network error on write", nil, &url.Error{Op: "Post", Err: errors.New("connection reset")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := checkRetry(context.Background(), tt.resp, tt.err)
+			if got != tt.want {
+				t.Errorf("expected retry=%v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", "7")
+	if wait := backoff(time.Second, 5*time.Second, 0, resp); wait != 7*time.Second {
+		t.Errorf("expected Retry-After to be honoured, got %s", wait)
+	}
+
+	if wait := backoff(time.Second, 5*time.Second, 10, nil); wait > 5*time.Second || wait < 4*time.Second {
+		t.Errorf("expected backoff bounded by max, got %s", wait)
+	}
+}
+
+func TestRateLimiter_PausesWhenExhausted(t *testing.T) {
+	limiter := newRateLimiter(0)
+
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("RateLimit-Limit", "600")
+	resp.Header.Set("RateLimit-Remaining", "5")
+	resp.Header.Set("RateLimit-Reset", "9999999999")
+	limiter.observe(resp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("expected requests to be held back until the rate limit resets")
+	}
+}
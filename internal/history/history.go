@@ -0,0 +1,239 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/conformity/helper/common"
+	"gitlab-mr-conformity-bot/internal/storage"
+)
+
+// Record is the audit trail entry of a single check run
+type Record struct {
+	ID              string `json:"id"`
+	ProjectID       int    `json:"project_id"`
+	MergeRequestIID int    `json:"merge_request_iid"`
+	Author          string `json:"author,omitempty"`
+	HeadSHA         string `json:"head_sha"`
+	TargetBranch    string `json:"target_branch"`
+	// ConfigVersion is a digest of the effective rules configuration
+	ConfigVersion  string            `json:"config_version"`
+	Passed         bool              `json:"passed"`
+	Rules          []RuleResult      `json:"rules"`
+	Approvals      *common.Approvals `json:"approvals,omitempty"`
+	ConfigWarnings []string          `json:"config_warnings,omitempty"`
+	StartedAt      time.Time         `json:"started_at"`
+	CompletedAt    time.Time         `json:"completed_at"`
+}
+
+// RuleResult is the outcome of one rule within a check run
+type RuleResult struct {
+	Name        string   `json:"name"`
+	Severity    string   `json:"severity"`
+	Passed      bool     `json:"passed"`
+	Errors      []string `json:"errors,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Reused is true when the outcome was taken from the previous check
+	Reused bool `json:"reused,omitempty"`
+}
+
+// Filter selects records
+type Filter struct {
+	// ProjectID selects a single project when not 0, all projects otherwise
+	ProjectID int
+	// MergeRequestIID selects a single merge request when not 0
+	MergeRequestIID int
+	// Since and Until bound the start time of a check, when set
+	Since time.Time
+	Until time.Time
+	// Limit keeps only the most recent records when greater than 0
+	Limit int
+}
+
+// projectsKey is the list of projects with a history. A project may be listed
+// more than once when replicas record its first check concurrently.
+const projectsKey = "history:projects"
+
+// Store persists check records in a storage.Storage, in one list per project
+type Store struct {
+	storage storage.Storage
+	// maxEntries caps the records kept per project, dropping the oldest; 0 keeps all
+	maxEntries int
+}
+
+func NewStore(store storage.Storage, maxEntries int) *Store {
+	return &Store{storage: store, maxEntries: maxEntries}
+}
+
+func projectKey(projectID int) string {
+	return fmt.Sprintf("history:%d", projectID)
+}
+
+// Add appends a record to the history of its project, dropping its oldest
+// records beyond the retention limit
+func (s *Store) Add(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	key := projectKey(record.ProjectID)
+	if !s.storage.Exists(key) {
+		if err := s.storage.Append(projectsKey, strconv.Itoa(record.ProjectID)); err != nil {
+			return fmt.Errorf("failed to index project: %w", err)
+		}
+	}
+	if err := s.storage.Append(key, data); err != nil {
+		return err
+	}
+	if s.maxEntries > 0 {
+		if err := s.storage.Trim(key, s.maxEntries); err != nil {
+			return fmt.Errorf("failed to trim history: %w", err)
+		}
+	}
+	return nil
+}
+
+// Projects returns the IDs of all projects with a history
+func (s *Store) Projects() ([]int, error) {
+	values, err := s.storage.List(projectsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects: %w", err)
+	}
+
+	seen := make(map[int]bool)
+	var projects []int
+	for _, value := range values {
+		id, err := strconv.Atoi(string(toBytes(value)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid project in history: %w", err)
+		}
+		if !seen[id] {
+			seen[id] = true
+			projects = append(projects, id)
+		}
+	}
+	return projects, nil
+}
+
+// Query returns the records matching filter, oldest first
+func (s *Store) Query(filter Filter) ([]Record, error) {
+	projects := []int{filter.ProjectID}
+	if filter.ProjectID == 0 {
+		var err error
+		if projects, err = s.Projects(); err != nil {
+			return nil, err
+		}
+	}
+
+	var records []Record
+	for _, projectID := range projects {
+		projectRecords, err := s.queryProject(projectID, filter)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, projectRecords...)
+	}
+	if len(projects) > 1 {
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].StartedAt.Before(records[j].StartedAt)
+		})
+	}
+
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+	return records, nil
+}
+
+// queryProject reads the records of a project newest first. Records are
+// appended as their check completes, so reading stops at the first record
+// that completed before filter.Since, or once filter.Limit records matched.
+func (s *Store) queryProject(projectID int, filter Filter) ([]Record, error) {
+	var records []Record
+	var decodeErr error
+	// The storage may pass a record twice while the list grows
+	seen := make(map[string]bool)
+	err := s.storage.ListReverse(projectKey(projectID), func(value interface{}) bool {
+		data := toBytes(value)
+		if data == nil {
+			decodeErr = fmt.Errorf("unexpected history value of type %T", value)
+			return false
+		}
+
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			decodeErr = fmt.Errorf("failed to decode record: %w", err)
+			return false
+		}
+		if !filter.Since.IsZero() && record.completedAt().Before(filter.Since) {
+			return false
+		}
+		if seen[record.ID] {
+			return true
+		}
+		seen[record.ID] = true
+
+		if filter.matches(&record) {
+			records = append(records, record)
+		}
+		return filter.Limit <= 0 || len(records) < filter.Limit
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	slices.Reverse(records)
+	return records, nil
+}
+
+// completedAt returns when the check completed, or started for records without completion time
+func (r *Record) completedAt() time.Time {
+	if r.CompletedAt.IsZero() {
+		return r.StartedAt
+	}
+	return r.CompletedAt
+}
+
+// toBytes returns the bytes of a stored value; the memory storage keeps values as given
+func toBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+func (f Filter) matches(record *Record) bool {
+	if f.MergeRequestIID != 0 && record.MergeRequestIID != f.MergeRequestIID {
+		return false
+	}
+	if !f.Since.IsZero() && record.StartedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !record.StartedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// WriteJSONLines writes one JSON encoded record per line
+func WriteJSONLines(w io.Writer, records []Record) error {
+	encoder := json.NewEncoder(w)
+	for i := range records {
+		if err := encoder.Encode(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/storage"
+)
+
+func TestStore_Query(t *testing.T) {
+	store := NewStore(storage.NewMemoryStorage(), 0)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []*Record{
+		{ID: "1", ProjectID: 1, MergeRequestIID: 10, StartedAt: start},
+		{ID: "2", ProjectID: 1, MergeRequestIID: 11, StartedAt: start.Add(time.Hour)},
+		{ID: "3", ProjectID: 1, MergeRequestIID: 10, StartedAt: start.Add(2 * time.Hour), Passed: true},
+		{ID: "4", ProjectID: 2, MergeRequestIID: 10, StartedAt: start},
+	}
+	for _, record := range records {
+		if err := store.Add(record); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"project", Filter{ProjectID: 1}, []string{"1", "2", "3"}},
+		{"merge request", Filter{ProjectID: 1, MergeRequestIID: 10}, []string{"1", "3"}},
+		{"time range", Filter{ProjectID: 1, Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)}, []string{"2"}},
+		{"limit keeps latest", Filter{ProjectID: 1, Limit: 2}, []string{"2", "3"}},
+		{"unknown project", Filter{ProjectID: 3}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			var ids []string
+			for _, record := range got {
+				ids = append(ids, record.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected records %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestStore_AddTrimsToMaxEntries(t *testing.T) {
+	store := NewStore(storage.NewMemoryStorage(), 2)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, id := range []string{"1", "2", "3"} {
+		if err := store.Add(&Record{ID: id, ProjectID: 1, StartedAt: start.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := store.Add(&Record{ID: "4", ProjectID: 2, StartedAt: start}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	got, err := store.Query(Filter{ProjectID: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "2" || got[1].ID != "3" {
+		t.Errorf("expected the 2 latest records of the project, got %v", got)
+	}
+	if got, _ := store.Query(Filter{ProjectID: 2}); len(got) != 1 {
+		t.Errorf("expected other projects to keep their records, got %d", len(got))
+	}
+}
+
+// countingStorage counts the list values read from a storage
+type countingStorage struct {
+	storage.Storage
+	read int
+}
+
+func (s *countingStorage) ListReverse(key string, fn func(value interface{}) bool) error {
+	return s.Storage.ListReverse(key, func(value interface{}) bool {
+		s.read++
+		return fn(value)
+	})
+}
+
+func TestStore_QueryStopsAtSince(t *testing.T) {
+	counting := &countingStorage{Storage: storage.NewMemoryStorage()}
+	store := NewStore(counting, 0)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := range 10 {
+		startedAt := start.Add(time.Duration(i) * time.Hour)
+		record := &Record{ID: strconv.Itoa(i), ProjectID: 1, StartedAt: startedAt, CompletedAt: startedAt.Add(time.Minute)}
+		if err := store.Add(record); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	got, err := store.Query(Filter{ProjectID: 1, Since: start.Add(8 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "8" || got[1].ID != "9" {
+		t.Errorf("expected the 2 latest records oldest first, got %v", got)
+	}
+	// The records of hours 9 and 8, and the first one completed before since
+	if counting.read != 3 {
+		t.Errorf("expected reading to stop at since, read %d records", counting.read)
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	records := []Record{{ID: "1", Rules: []RuleResult{{Name: "Title Validation", Passed: true}}}, {ID: "2"}}
+	if err := WriteJSONLines(&buf, records); err != nil {
+		t.Fatalf("WriteJSONLines failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var record Record
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil || record.ID != "1" || record.Rules[0].Name != "Title Validation" {
+		t.Errorf("unexpected first line %q (%v)", lines[0], err)
+	}
+}
@@ -0,0 +1,229 @@
+package history
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// FailureRate aggregates checks of one rule, project or author
+type FailureRate struct {
+	// Key is the rule name, project ID or author username
+	Key           string  `json:"key"`
+	Checks        int     `json:"checks"`
+	Failures      int     `json:"failures"`
+	FailureRate   float64 `json:"failure_rate"`
+	MergeRequests int     `json:"merge_requests"`
+}
+
+// TimeToGreen describes how long a merge request stayed non-compliant, from
+// its first failed check to the first passing check after it
+type TimeToGreen struct {
+	ProjectID       int        `json:"project_id"`
+	MergeRequestIID int        `json:"merge_request_iid"`
+	Author          string     `json:"author,omitempty"`
+	FirstFailedAt   time.Time  `json:"first_failed_at"`
+	GreenAt         *time.Time `json:"green_at,omitempty"`
+	// DurationSeconds is 0 while the merge request is still failing
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// TimeToGreenSummary aggregates the merge requests that turned green
+type TimeToGreenSummary struct {
+	MergeRequests int     `json:"merge_requests"`
+	Green         int     `json:"green"`
+	StillFailing  int     `json:"still_failing"`
+	MeanSeconds   float64 `json:"mean_seconds"`
+	MedianSeconds float64 `json:"median_seconds"`
+	MaxSeconds    float64 `json:"max_seconds"`
+}
+
+// mergeRequestKey identifies a merge request across projects
+type mergeRequestKey struct {
+	projectID int
+	iid       int
+}
+
+// rateCounter accumulates a FailureRate
+type rateCounter struct {
+	checks        int
+	failures      int
+	mergeRequests map[mergeRequestKey]bool
+}
+
+func (c *rateCounter) add(record *Record, failed bool) {
+	c.checks++
+	if failed {
+		c.failures++
+	}
+	c.mergeRequests[mergeRequestKey{record.ProjectID, record.MergeRequestIID}] = true
+}
+
+// RuleFailureRates returns how often each rule failed among the checks it ran in
+func RuleFailureRates(records []Record) []FailureRate {
+	return failureRates(records, func(record *Record, count func(key string, failed bool)) {
+		for _, rule := range record.Rules {
+			count(rule.Name, !rule.Passed)
+		}
+	})
+}
+
+// ProjectFailureRates returns how often checks of each project failed
+func ProjectFailureRates(records []Record) []FailureRate {
+	return failureRates(records, func(record *Record, count func(key string, failed bool)) {
+		count(strconv.Itoa(record.ProjectID), !record.Passed)
+	})
+}
+
+// AuthorFailureRates returns how often checks of merge requests of each author failed
+func AuthorFailureRates(records []Record) []FailureRate {
+	return failureRates(records, func(record *Record, count func(key string, failed bool)) {
+		count(record.Author, !record.Passed)
+	})
+}
+
+// failureRates groups records with group and sorts the result worst first
+func failureRates(records []Record, group func(record *Record, count func(key string, failed bool))) []FailureRate {
+	counters := make(map[string]*rateCounter)
+	for i := range records {
+		record := &records[i]
+		group(record, func(key string, failed bool) {
+			counter, ok := counters[key]
+			if !ok {
+				counter = &rateCounter{mergeRequests: make(map[mergeRequestKey]bool)}
+				counters[key] = counter
+			}
+			counter.add(record, failed)
+		})
+	}
+
+	rates := make([]FailureRate, 0, len(counters))
+	for key, counter := range counters {
+		rates = append(rates, FailureRate{
+			Key:           key,
+			Checks:        counter.checks,
+			Failures:      counter.failures,
+			FailureRate:   float64(counter.failures) / float64(counter.checks),
+			MergeRequests: len(counter.mergeRequests),
+		})
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].FailureRate != rates[j].FailureRate {
+			return rates[i].FailureRate > rates[j].FailureRate
+		}
+		if rates[i].Checks != rates[j].Checks {
+			return rates[i].Checks > rates[j].Checks
+		}
+		return rates[i].Key < rates[j].Key
+	})
+	return rates
+}
+
+// TimesToGreen returns the time to green of every merge request that failed a check
+func TimesToGreen(records []Record) ([]TimeToGreen, TimeToGreenSummary) {
+	sorted := append([]Record{}, records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartedAt.Before(sorted[j].StartedAt)
+	})
+
+	var order []mergeRequestKey
+	stretches := make(map[mergeRequestKey]*TimeToGreen)
+	for _, record := range sorted {
+		key := mergeRequestKey{record.ProjectID, record.MergeRequestIID}
+		stretch, ok := stretches[key]
+		switch {
+		case !ok && !record.Passed:
+			stretches[key] = &TimeToGreen{
+				ProjectID:       record.ProjectID,
+				MergeRequestIID: record.MergeRequestIID,
+				Author:          record.Author,
+				FirstFailedAt:   record.StartedAt,
+			}
+			order = append(order, key)
+		case ok && stretch.GreenAt == nil && record.Passed:
+			greenAt := record.StartedAt
+			stretch.GreenAt = &greenAt
+			stretch.DurationSeconds = greenAt.Sub(stretch.FirstFailedAt).Seconds()
+		}
+	}
+
+	var summary TimeToGreenSummary
+	var durations []float64
+	rows := make([]TimeToGreen, 0, len(order))
+	for _, key := range order {
+		stretch := stretches[key]
+		rows = append(rows, *stretch)
+		if stretch.GreenAt == nil {
+			summary.StillFailing++
+			continue
+		}
+		durations = append(durations, stretch.DurationSeconds)
+	}
+
+	summary.MergeRequests = len(rows)
+	summary.Green = len(durations)
+	if len(durations) > 0 {
+		sort.Float64s(durations)
+		total := 0.0
+		for _, d := range durations {
+			total += d
+		}
+		summary.MeanSeconds = total / float64(len(durations))
+		summary.MaxSeconds = durations[len(durations)-1]
+		mid := len(durations) / 2
+		if len(durations)%2 == 0 {
+			summary.MedianSeconds = (durations[mid-1] + durations[mid]) / 2
+		} else {
+			summary.MedianSeconds = durations[mid]
+		}
+	}
+
+	return rows, summary
+}
+
+// WriteFailureRatesCSV writes failure rates as CSV, naming the key column keyName
+func WriteFailureRatesCSV(w io.Writer, keyName string, rates []FailureRate) error {
+	rows := [][]string{{keyName, "checks", "failures", "failure_rate", "merge_requests"}}
+	for _, rate := range rates {
+		rows = append(rows, []string{
+			rate.Key,
+			strconv.Itoa(rate.Checks),
+			strconv.Itoa(rate.Failures),
+			strconv.FormatFloat(rate.FailureRate, 'f', 4, 64),
+			strconv.Itoa(rate.MergeRequests),
+		})
+	}
+	return writeCSV(w, rows)
+}
+
+// WriteTimesToGreenCSV writes times to green as CSV
+func WriteTimesToGreenCSV(w io.Writer, times []TimeToGreen) error {
+	rows := [][]string{{"project_id", "merge_request_iid", "author", "first_failed_at", "green_at", "duration_seconds"}}
+	for _, t := range times {
+		greenAt := ""
+		if t.GreenAt != nil {
+			greenAt = t.GreenAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(t.ProjectID),
+			strconv.Itoa(t.MergeRequestIID),
+			t.Author,
+			t.FirstFailedAt.Format(time.RFC3339),
+			greenAt,
+			strconv.FormatFloat(t.DurationSeconds, 'f', 0, 64),
+		})
+	}
+	return writeCSV(w, rows)
+}
+
+func writeCSV(w io.Writer, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package history
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuleFailureRates(t *testing.T) {
+	records := []Record{
+		{ProjectID: 1, MergeRequestIID: 1, Rules: []RuleResult{{Name: "Title", Passed: false}, {Name: "Branch", Passed: true}}},
+		{ProjectID: 1, MergeRequestIID: 1, Rules: []RuleResult{{Name: "Title", Passed: true}, {Name: "Branch", Passed: true}}},
+		{ProjectID: 2, MergeRequestIID: 1, Rules: []RuleResult{{Name: "Title", Passed: false}}},
+	}
+
+	rates := RuleFailureRates(records)
+	if len(rates) != 2 {
+		t.Fatalf("expected 2 rules, got %+v", rates)
+	}
+	title := rates[0]
+	if title.Key != "Title" || title.Checks != 3 || title.Failures != 2 || title.MergeRequests != 2 {
+		t.Errorf("unexpected title rate: %+v", title)
+	}
+	if rates[1].Key != "Branch" || rates[1].FailureRate != 0 {
+		t.Errorf("unexpected branch rate: %+v", rates[1])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFailureRatesCSV(&buf, "rule", rates); err != nil {
+		t.Fatalf("WriteFailureRatesCSV failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "rule,checks,failures,failure_rate,merge_requests\nTitle,3,2,0.6667,2\n") {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestTimesToGreen(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{ProjectID: 1, MergeRequestIID: 1, Passed: true, StartedAt: start},
+		{ProjectID: 1, MergeRequestIID: 2, Passed: false, StartedAt: start},
+		{ProjectID: 1, MergeRequestIID: 2, Passed: false, StartedAt: start.Add(time.Hour)},
+		{ProjectID: 1, MergeRequestIID: 2, Passed: true, StartedAt: start.Add(2 * time.Hour)},
+		{ProjectID: 1, MergeRequestIID: 3, Passed: false, StartedAt: start.Add(time.Hour)},
+		{ProjectID: 2, MergeRequestIID: 2, Passed: false, StartedAt: start},
+		{ProjectID: 2, MergeRequestIID: 2, Passed: true, StartedAt: start.Add(4 * time.Hour)},
+	}
+
+	times, summary := TimesToGreen(records)
+	if len(times) != 3 {
+		t.Fatalf("expected 3 merge requests that failed, got %+v", times)
+	}
+	if times[0].MergeRequestIID != 2 || times[0].DurationSeconds != 7200 {
+		t.Errorf("unexpected first merge request: %+v", times[0])
+	}
+	if summary.Green != 2 || summary.StillFailing != 1 || summary.MedianSeconds != 10800 || summary.MaxSeconds != 14400 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
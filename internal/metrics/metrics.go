@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mr_conform"
+
+var (
+	// WebhooksReceived counts webhooks by GitLab event type
+	WebhooksReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhooks_received_total",
+		Help:      "Webhooks received, by event type.",
+	}, []string{"event"})
+
+	// WebhookDuplicates counts webhook deliveries ignored as retries of an accepted delivery
+	WebhookDuplicates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_duplicates_total",
+		Help:      "Duplicate webhook deliveries ignored, by event type.",
+	}, []string{"event"})
+
+	// ChecksTotal counts checks by result: passed, failed, cached or error
+	ChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checks_total",
+		Help:      "Merge request checks, by result (passed, failed, cached or error).",
+	}, []string{"result"})
+
+	// CheckDuration observes the duration of checks by result
+	CheckDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "check_duration_seconds",
+		Help:      "Duration of merge request checks, by result.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"result"})
+
+	// RuleResults counts rule runs by rule and result: passed, failed or error.
+	// Outcomes reused from the cache are not counted.
+	RuleResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rule_results_total",
+		Help:      "Rule runs, by rule and result (passed, failed or error).",
+	}, []string{"rule", "result"})
+
+	// GitLabRequests counts GitLab API requests by method and status code, "error" for network errors
+	GitLabRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gitlab_requests_total",
+		Help:      "GitLab API requests, by method and status code (error for network errors).",
+	}, []string{"method", "code"})
+
+	// GitLabRequestDuration observes the latency of single GitLab API requests, retries included separately
+	GitLabRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "gitlab_request_duration_seconds",
+		Help:      "Latency of GitLab API requests, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// AsanaRequestDuration observes the latency of Asana task lookups by result
+	AsanaRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "asana_request_duration_seconds",
+		Help:      "Latency of Asana task lookups, by result (found, not_found or error).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+)
+
+// ObserveGitLabRequest records a GitLab API request that started at start
+func ObserveGitLabRequest(method string, resp *http.Response, err error, start time.Time) {
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	GitLabRequests.WithLabelValues(method, code).Inc()
+	GitLabRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// ObserveCheck records a check that started at start
+func ObserveCheck(result string, start time.Time) {
+	ChecksTotal.WithLabelValues(result).Inc()
+	CheckDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves the metrics of the default registry
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
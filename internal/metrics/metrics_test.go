@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/queue"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveGitLabRequest(t *testing.T) {
+	before := testutil.ToFloat64(GitLabRequests.WithLabelValues("GET", "429"))
+	ObserveGitLabRequest("GET", &http.Response{StatusCode: http.StatusTooManyRequests}, nil, time.Now())
+	if got := testutil.ToFloat64(GitLabRequests.WithLabelValues("GET", "429")) - before; got != 1 {
+		t.Errorf("expected one request with code 429, got %v", got)
+	}
+
+	before = testutil.ToFloat64(GitLabRequests.WithLabelValues("POST", "error"))
+	ObserveGitLabRequest("POST", nil, errors.New("connection refused"), time.Now())
+	if got := testutil.ToFloat64(GitLabRequests.WithLabelValues("POST", "error")) - before; got != 1 {
+		t.Errorf("expected one failed request, got %v", got)
+	}
+}
+
+type fakeQueueStats struct {
+	stats *queue.QueueStats
+	err   error
+}
+
+func (f *fakeQueueStats) GetQueueStats(c context.Context) (*queue.QueueStats, error) {
+	return f.stats, f.err
+}
+
+func TestQueueCollector(t *testing.T) {
+	source := &fakeQueueStats{stats: &queue.QueueStats{TotalQueues: 2, TotalJobs: 5, ProcessingJobs: 1, Retries: 3, DeadJobs: 1}}
+	registry := prometheus.NewRegistry()
+	collector := newQueueCollector(source)
+	registry.MustRegister(collector)
+
+	expected := `
+# HELP mr_conform_queue_jobs Jobs waiting in the queues.
+# TYPE mr_conform_queue_jobs gauge
+mr_conform_queue_jobs 5
+# HELP mr_conform_queue_dead_jobs_total Jobs dropped after their last attempt.
+# TYPE mr_conform_queue_dead_jobs_total counter
+mr_conform_queue_dead_jobs_total 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "mr_conform_queue_jobs", "mr_conform_queue_dead_jobs_total"); err != nil {
+		t.Error(err)
+	}
+
+	source.err = errors.New("redis unavailable")
+	if count := testutil.CollectAndCount(collector); count != 1 {
+		t.Errorf("expected only the up metric when stats fail, got %d metrics", count)
+	}
+}
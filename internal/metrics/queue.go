@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/queue"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// queueStatsTimeout bounds reading queue statistics during a scrape
+const queueStatsTimeout = 5 * time.Second
+
+// QueueStatsSource provides the statistics of the webhook queue
+type QueueStatsSource interface {
+	GetQueueStats(c context.Context) (*queue.QueueStats, error)
+}
+
+// queueCollector reads queue statistics on every scrape, so all replicas
+// report the state of the shared queue
+type queueCollector struct {
+	source QueueStatsSource
+
+	queues     *prometheus.Desc
+	jobs       *prometheus.Desc
+	processing *prometheus.Desc
+	delayed    *prometheus.Desc
+	retries    *prometheus.Desc
+	deadJobs   *prometheus.Desc
+	coalesced  *prometheus.Desc
+	reaped     *prometheus.Desc
+	up         *prometheus.Desc
+}
+
+// RegisterQueueCollector exposes the statistics of the webhook queue
+func RegisterQueueCollector(source QueueStatsSource) error {
+	return prometheus.Register(newQueueCollector(source))
+}
+
+func newQueueCollector(source QueueStatsSource) *queueCollector {
+	return &queueCollector{
+		source:     source,
+		queues:     prometheus.NewDesc(namespace+"_queue_queues", "Merge request queues.", nil, nil),
+		jobs:       prometheus.NewDesc(namespace+"_queue_jobs", "Jobs waiting in the queues.", nil, nil),
+		processing: prometheus.NewDesc(namespace+"_queue_processing_jobs", "Jobs being processed.", nil, nil),
+		delayed:    prometheus.NewDesc(namespace+"_queue_delayed_jobs", "Failed jobs waiting for their retry.", nil, nil),
+		retries:    prometheus.NewDesc(namespace+"_queue_retries_total", "Jobs requeued after a failure.", nil, nil),
+		deadJobs:   prometheus.NewDesc(namespace+"_queue_dead_jobs_total", "Jobs dropped after their last attempt.", nil, nil),
+		coalesced:  prometheus.NewDesc(namespace+"_queue_coalesced_jobs_total", "Jobs superseded by a newer job of the same merge request.", nil, nil),
+		reaped:     prometheus.NewDesc(namespace+"_queue_reaped_jobs_total", "Jobs requeued after their worker crashed or lost its lock.", nil, nil),
+		up:         prometheus.NewDesc(namespace+"_queue_up", "Whether the queue statistics could be read.", nil, nil),
+	}
+}
+
+func (qc *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- qc.queues
+	ch <- qc.jobs
+	ch <- qc.processing
+	ch <- qc.delayed
+	ch <- qc.retries
+	ch <- qc.deadJobs
+	ch <- qc.coalesced
+	ch <- qc.reaped
+	ch <- qc.up
+}
+
+func (qc *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	c, cancel := context.WithTimeout(context.Background(), queueStatsTimeout)
+	defer cancel()
+
+	stats, err := qc.source.GetQueueStats(c)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(qc.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(qc.up, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(qc.queues, prometheus.GaugeValue, float64(stats.TotalQueues))
+	ch <- prometheus.MustNewConstMetric(qc.jobs, prometheus.GaugeValue, float64(stats.TotalJobs))
+	ch <- prometheus.MustNewConstMetric(qc.processing, prometheus.GaugeValue, float64(stats.ProcessingJobs))
+	ch <- prometheus.MustNewConstMetric(qc.delayed, prometheus.GaugeValue, float64(stats.DelayedJobs))
+	ch <- prometheus.MustNewConstMetric(qc.retries, prometheus.CounterValue, float64(stats.Retries))
+	ch <- prometheus.MustNewConstMetric(qc.deadJobs, prometheus.CounterValue, float64(stats.DeadJobs))
+	ch <- prometheus.MustNewConstMetric(qc.coalesced, prometheus.CounterValue, float64(stats.Coalesced))
+	ch <- prometheus.MustNewConstMetric(qc.reaped, prometheus.CounterValue, float64(stats.Reaped))
+}
@@ -0,0 +1,190 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrDeadJobNotFound is returned for a job that is not in the dead-letter queue
+var ErrDeadJobNotFound = errors.New("dead job not found")
+
+// DeadJob is a job that failed its last attempt, kept for inspection and replay
+type DeadJob struct {
+	Job *WebhookJob `json:"job"`
+	// Error is the error of the last attempt
+	Error    string `json:"error"`
+	FailedAt int64  `json:"failed_at"`
+}
+
+// The dead-letter queue keeps jobs in a hash by job ID, ordered by a sorted
+// set scored by the time they failed
+func (qm *QueueManager) getDeadJobsKey() string {
+	return sharedKey(qm.deadPrefix, "jobs")
+}
+
+func (qm *QueueManager) getDeadIndexKey() string {
+	return sharedKey(qm.deadPrefix, "index")
+}
+
+func (qm *QueueManager) addDeadJob(c context.Context, job *WebhookJob) error {
+	dead := &DeadJob{Job: job, Error: job.LastError, FailedAt: time.Now().Unix()}
+	data, err := json.Marshal(dead)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead job: %w", err)
+	}
+
+	if _, err := qm.redis.TxPipelined(c, func(pipe redis.Pipeliner) error {
+		pipe.HSet(c, qm.getDeadJobsKey(), job.ID, data)
+		pipe.ZAdd(c, qm.getDeadIndexKey(), &redis.Z{Score: float64(dead.FailedAt), Member: job.ID})
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if qm.maxDeadJobs > 0 {
+		return qm.trimDeadJobs(c)
+	}
+	return nil
+}
+
+// trimDeadJobs drops the oldest dead jobs beyond maxDeadJobs
+func (qm *QueueManager) trimDeadJobs(c context.Context) error {
+	excess, err := qm.redis.ZRange(c, qm.getDeadIndexKey(), 0, -qm.maxDeadJobs-1).Result()
+	if err != nil || len(excess) == 0 {
+		return err
+	}
+	_, err = qm.redis.TxPipelined(c, func(pipe redis.Pipeliner) error {
+		pipe.HDel(c, qm.getDeadJobsKey(), excess...)
+		pipe.ZRem(c, qm.getDeadIndexKey(), toMembers(excess)...)
+		return nil
+	})
+	return err
+}
+
+// ListDeadJobs returns dead jobs, most recent first, and the total number of dead jobs
+func (qm *QueueManager) ListDeadJobs(c context.Context, offset, limit int) ([]*DeadJob, int64, error) {
+	total, err := qm.redis.ZCard(c, qm.getDeadIndexKey()).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead jobs: %w", err)
+	}
+	if limit <= 0 {
+		return []*DeadJob{}, total, nil
+	}
+
+	ids, err := qm.redis.ZRevRange(c, qm.getDeadIndexKey(), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead jobs: %w", err)
+	}
+	if len(ids) == 0 {
+		return []*DeadJob{}, total, nil
+	}
+
+	values, err := qm.redis.HMGet(c, qm.getDeadJobsKey(), ids...).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read dead jobs: %w", err)
+	}
+
+	jobs := make([]*DeadJob, 0, len(values))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			// Removed since the index was read
+			continue
+		}
+		dead, err := decodeDeadJob(data)
+		if err != nil {
+			qm.log.Warn("Skipping undecodable dead job", "jobId", ids[i], "error", err)
+			continue
+		}
+		jobs = append(jobs, dead)
+	}
+	return jobs, total, nil
+}
+
+// GetDeadJob returns a dead job by ID
+func (qm *QueueManager) GetDeadJob(c context.Context, jobID string) (*DeadJob, error) {
+	data, err := qm.redis.HGet(c, qm.getDeadJobsKey(), jobID).Result()
+	if err == redis.Nil {
+		return nil, ErrDeadJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead job: %w", err)
+	}
+	return decodeDeadJob(data)
+}
+
+// ReplayDeadJob queues a dead job again with a fresh set of attempts, then
+// removes it from the dead-letter queue. A job replayed concurrently is
+// queued twice and coalesced by its MR queue; a failed push loses nothing.
+func (qm *QueueManager) ReplayDeadJob(c context.Context, jobID string) (*WebhookJob, error) {
+	dead, err := qm.GetDeadJob(c, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	job := dead.Job
+	job.Attempts = 0
+	job.MaxAttempts = qm.maxRetries
+	job.AttemptedAt = nil
+	job.LastError = ""
+	job.CreatedAt = time.Now().Unix()
+	if err := qm.pushJob(c, job, false); err != nil {
+		return nil, err
+	}
+	if _, err := qm.DeleteDeadJob(c, jobID); err != nil {
+		return nil, fmt.Errorf("replayed dead job was not removed: %w", err)
+	}
+
+	qm.log.Info("Replayed dead job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID)
+	return job, nil
+}
+
+// DeleteDeadJob removes a job from the dead-letter queue and reports whether it was there
+func (qm *QueueManager) DeleteDeadJob(c context.Context, jobID string) (bool, error) {
+	var deleted *redis.IntCmd
+	if _, err := qm.redis.TxPipelined(c, func(pipe redis.Pipeliner) error {
+		deleted = pipe.HDel(c, qm.getDeadJobsKey(), jobID)
+		pipe.ZRem(c, qm.getDeadIndexKey(), jobID)
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("failed to delete dead job: %w", err)
+	}
+	return deleted.Val() > 0, nil
+}
+
+// PurgeDeadJobs empties the dead-letter queue and returns the number of removed jobs
+func (qm *QueueManager) PurgeDeadJobs(c context.Context) (int64, error) {
+	var count *redis.IntCmd
+	if _, err := qm.redis.TxPipelined(c, func(pipe redis.Pipeliner) error {
+		count = pipe.HLen(c, qm.getDeadJobsKey())
+		pipe.Del(c, qm.getDeadJobsKey(), qm.getDeadIndexKey())
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("failed to purge dead jobs: %w", err)
+	}
+	return count.Val(), nil
+}
+
+func decodeDeadJob(data string) (*DeadJob, error) {
+	var dead DeadJob
+	if err := json.Unmarshal([]byte(data), &dead); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead job: %w", err)
+	}
+	if dead.Job == nil {
+		return nil, errors.New("dead job without job")
+	}
+	return &dead, nil
+}
+
+func toMembers(ids []string) []interface{} {
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+	return members
+}
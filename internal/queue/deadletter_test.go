@@ -0,0 +1,111 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func addDeadJobs(t *testing.T, qm *QueueManager, ids ...string) {
+	t.Helper()
+	for i, id := range ids {
+		job := &WebhookJob{ID: id, ProjectID: "1", MergeRequestIID: fmt.Sprint(i + 1), Attempts: 3, LastError: "boom"}
+		if err := qm.addDeadJob(context.Background(), job); err != nil {
+			t.Fatalf("failed to add dead job: %v", err)
+		}
+	}
+}
+
+func TestDeadJobs_AddTrimAndList(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, &Config{MaxDeadJobs: 2})
+
+	addDeadJobs(t, qm, "first")
+	addDeadJobs(t, qm, "second", "third")
+
+	jobs, total, err := qm.ListDeadJobs(c, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(jobs) != 2 {
+		t.Fatalf("expected 2 dead jobs after trimming, got %d of %d", len(jobs), total)
+	}
+	for _, dead := range jobs {
+		if dead.Job.ID == "first" {
+			t.Error("expected the oldest dead job to be trimmed")
+		}
+		if dead.Error != "boom" {
+			t.Errorf("expected the last error to be kept, got %q", dead.Error)
+		}
+	}
+	if _, err := qm.GetDeadJob(c, "first"); !errors.Is(err, ErrDeadJobNotFound) {
+		t.Errorf("expected the trimmed job to be gone, got %v", err)
+	}
+
+	page, total, err := qm.ListDeadJobs(c, 1, 10)
+	if err != nil || total != 2 || len(page) != 1 {
+		t.Errorf("expected one job past the offset, got %d of %d (%v)", len(page), total, err)
+	}
+}
+
+func TestDeadJobs_Replay(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, &Config{MaxRetries: 5})
+	addDeadJobs(t, qm, "dead")
+
+	job, err := qm.ReplayDeadJob(c, "dead")
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if job.Attempts != 0 || job.MaxAttempts != 5 || job.LastError != "" {
+		t.Errorf("expected a fresh set of attempts, got %+v", job)
+	}
+	if _, err := qm.GetDeadJob(c, "dead"); !errors.Is(err, ErrDeadJobNotFound) {
+		t.Errorf("expected the replayed job to leave the dead-letter queue, got %v", err)
+	}
+	queued, _, err := qm.dequeueJobs(c, "1", "1")
+	if err != nil || len(queued) != 1 || queued[0].ID != "dead" {
+		t.Errorf("expected the replayed job to be queued, got %v (%v)", queued, err)
+	}
+
+	if _, err := qm.ReplayDeadJob(c, "dead"); !errors.Is(err, ErrDeadJobNotFound) {
+		t.Errorf("expected a second replay to find no job, got %v", err)
+	}
+}
+
+func TestDeadJobs_ReplayKeepsJobWhenPushFails(t *testing.T) {
+	c := context.Background()
+	qm, server := newTestQueueManager(t, nil)
+	addDeadJobs(t, qm, "dead")
+
+	// The MR queue has the wrong type, so the job cannot be pushed
+	server.Set(qm.getQueueKey("1", "1"), "not a list")
+
+	if _, err := qm.ReplayDeadJob(c, "dead"); err == nil {
+		t.Fatal("expected the replay to fail")
+	}
+	if _, err := qm.GetDeadJob(c, "dead"); err != nil {
+		t.Errorf("expected the job to stay in the dead-letter queue, got %v", err)
+	}
+}
+
+func TestDeadJobs_DeleteAndPurge(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, nil)
+	addDeadJobs(t, qm, "a", "b", "c")
+
+	if deleted, err := qm.DeleteDeadJob(c, "a"); err != nil || !deleted {
+		t.Errorf("expected the job to be deleted, got %v (%v)", deleted, err)
+	}
+	if deleted, err := qm.DeleteDeadJob(c, "a"); err != nil || deleted {
+		t.Errorf("expected a deleted job not to be found, got %v (%v)", deleted, err)
+	}
+
+	if count, err := qm.PurgeDeadJobs(c); err != nil || count != 2 {
+		t.Errorf("expected 2 purged jobs, got %d (%v)", count, err)
+	}
+	if _, total, err := qm.ListDeadJobs(c, 0, 10); err != nil || total != 0 {
+		t.Errorf("expected an empty dead-letter queue, got %d (%v)", total, err)
+	}
+}
@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"gitlab-mr-conformity-bot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ErrQueueFull is returned when a bounded queue cannot take another job
+var ErrQueueFull = errors.New("queue is full")
+
+// JobQueue queues webhook jobs for asynchronous processing. Jobs of one MR are
+// processed in order and coalesced; jobs of different MRs run in parallel.
+type JobQueue interface {
+	EnqueueWebhook(c context.Context, projectID, mergeRequestIID, webhookType string, payload *gitlabapi.MergeEvent) (string, error)
+	StartProcessor(c context.Context, processor JobProcessor)
+	// StopProcessor waits for jobs in flight until c is done, then cancels them
+	StopProcessor(c context.Context) error
+	GetQueueStats(c context.Context) (*QueueStats, error)
+	Health(c context.Context) error
+
+	ListDeadJobs(c context.Context, offset, limit int) ([]*DeadJob, int64, error)
+	GetDeadJob(c context.Context, jobID string) (*DeadJob, error)
+	ReplayDeadJob(c context.Context, jobID string) (*WebhookJob, error)
+	DeleteDeadJob(c context.Context, jobID string) (bool, error)
+	PurgeDeadJobs(c context.Context) (int64, error)
+
+	Close() error
+}
+
+var (
+	_ JobQueue = (*QueueManager)(nil)
+	_ JobQueue = (*MemoryQueue)(nil)
+)
+
+// startJobSpan starts the span of a job attempt, linked to the webhook request that queued it
+func startJobSpan(c context.Context, job *WebhookJob) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("queue.job_id", job.ID),
+			attribute.Int("queue.attempt", job.Attempts+1),
+			attribute.String("gitlab.project_id", job.ProjectID),
+			attribute.String("gitlab.merge_request_iid", job.MergeRequestIID),
+		),
+	}
+	if link, ok := tracing.Link(job.TraceContext); ok {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	return tracing.Start(c, "queue.process_job", opts...)
+}
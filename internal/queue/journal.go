@@ -0,0 +1,206 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// compactAfter is the number of records appended to a journal before it is
+// rewritten with the live jobs only
+const compactAfter = 1000
+
+// Journal operations
+const (
+	journalAdd    = "add"
+	journalRemove = "remove"
+	journalDead   = "dead"
+)
+
+// journalRecord is one line of the journal. Records of the same job replace
+// each other, so the last one describes its state.
+type journalRecord struct {
+	Op  string      `json:"op"`
+	ID  string      `json:"id,omitempty"`
+	Job *WebhookJob `json:"job,omitempty"`
+	// Due is when a delayed retry is due, as Unix milliseconds
+	Due  int64    `json:"due,omitempty"`
+	Dead *DeadJob `json:"dead,omitempty"`
+}
+
+// journal is an append-only log of the jobs of a MemoryQueue, replayed on start
+type journal struct {
+	path    string
+	file    *os.File
+	records int
+}
+
+// journalState holds the jobs recovered from a journal
+type journalState struct {
+	// pending holds queued, delayed and interrupted jobs, oldest first
+	pending []journalRecord
+	// dead holds dead jobs, oldest first
+	dead []*DeadJob
+}
+
+// openJournal replays the journal at path, creating it when missing, and
+// rewrites it with the recovered jobs only
+func openJournal(path string) (*journal, *journalState, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	state, err := replayJournal(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	j := &journal{path: path}
+	if err := j.rewrite(state.records()); err != nil {
+		return nil, nil, err
+	}
+	return j, state, nil
+}
+
+func replayJournal(path string) (*journalState, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &journalState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	pending := make(map[string]journalRecord)
+	dead := make(map[string]*DeadJob)
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A partial last line was cut off by a crash while writing it
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal: %w", err)
+		}
+
+		var record journalRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("failed to decode journal record: %w", err)
+		}
+		switch record.Op {
+		case journalAdd:
+			if record.Job != nil {
+				// A replayed dead job is queued again
+				delete(dead, record.Job.ID)
+				pending[record.Job.ID] = record
+			}
+		case journalDead:
+			if record.Dead != nil && record.Dead.Job != nil {
+				delete(pending, record.Dead.Job.ID)
+				dead[record.Dead.Job.ID] = record.Dead
+			}
+		case journalRemove:
+			delete(pending, record.ID)
+			delete(dead, record.ID)
+		}
+	}
+
+	state := &journalState{}
+	for _, record := range pending {
+		state.pending = append(state.pending, record)
+	}
+	sort.SliceStable(state.pending, func(i, j int) bool {
+		return state.pending[i].Job.CreatedAt < state.pending[j].Job.CreatedAt
+	})
+	for _, job := range dead {
+		state.dead = append(state.dead, job)
+	}
+	sort.SliceStable(state.dead, func(i, j int) bool {
+		return state.dead[i].FailedAt < state.dead[j].FailedAt
+	})
+	return state, nil
+}
+
+// records returns the records that recreate the state
+func (s *journalState) records() []journalRecord {
+	records := append([]journalRecord(nil), s.pending...)
+	for _, dead := range s.dead {
+		records = append(records, journalRecord{Op: journalDead, Dead: dead})
+	}
+	return records
+}
+
+// append writes a record and syncs it to disk
+func (j *journal) append(record journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal record: %w", err)
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	j.records++
+	return nil
+}
+
+// needsCompaction reports whether the journal grew enough to be rewritten,
+// given the number of live records
+func (j *journal) needsCompaction(live int) bool {
+	return j.records >= compactAfter && j.records >= 2*live
+}
+
+// rewrite replaces the journal with records, atomically
+func (j *journal) rewrite(records []journalRecord) error {
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create journal: %w", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for i := range records {
+		if err := encoder.Encode(&records[i]); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write journal: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return fmt.Errorf("failed to replace journal: %w", err)
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.records = len(records)
+	return nil
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
@@ -0,0 +1,259 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// errInterrupted is recorded for an attempt that never finished, because its
+// worker crashed or lost the lock of the MR
+var errInterrupted = errors.New("processing was interrupted")
+
+// maxReapedPerSweep bounds the stuck jobs requeued by one sweep of the dispatcher
+const maxReapedPerSweep = 100
+
+// mrLock is the lock of an MR queue. Its value is a fencing token, unique
+// across replicas, so a holder whose lock expired can no longer renew or
+// release the lock of the next holder. While held, a heartbeat renews the
+// lock and extends the visibility timeout of the job being processed.
+type mrLock struct {
+	qm            *QueueManager
+	key           string
+	processingKey string
+	token         int64
+
+	mu      sync.Mutex
+	jobData string
+	lost    bool
+
+	// cancel stops the work done under the lock once it is lost
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// renewScript extends the lock while it holds the token, and the visibility
+// timeout of the job being processed under it, if any
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+if ARGV[3] ~= "" then
+	redis.call("ZADD", KEYS[2], "XX", ARGV[4], ARGV[3])
+end
+return 1
+`)
+
+// releaseScript deletes the lock only while it holds the token
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// acquireLock takes the lock of an MR queue and starts its heartbeat. It
+// returns nil when the lock is held by another worker. The returned context
+// is cancelled when the lock is lost.
+func (qm *QueueManager) acquireLock(c context.Context, projectID, mergeRequestIID string) (*mrLock, context.Context, error) {
+	lockKey := qm.getLockKey(projectID, mergeRequestIID)
+	token, err := qm.redis.Incr(c, qm.getFenceKey()).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	locked, err := qm.redis.SetNX(c, lockKey, token, qm.defaultLockTTL).Result()
+	if err != nil || !locked {
+		return nil, nil, err
+	}
+
+	lockCtx, cancel := context.WithCancel(c)
+	lock := &mrLock{
+		qm:            qm,
+		key:           lockKey,
+		processingKey: qm.getProcessingKey(projectID, mergeRequestIID),
+		token:         token,
+		cancel:        cancel,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go lock.heartbeat(lockCtx)
+	return lock, lockCtx, nil
+}
+
+// heartbeat renews the lock three times per TTL. The lock counts as lost
+// when another holder took it or it could not be renewed within its TTL.
+func (l *mrLock) heartbeat(c context.Context) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.qm.defaultLockTTL / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
+
+		held, err := l.renew(c)
+		switch {
+		case err == nil && held:
+			renewed = time.Now()
+			continue
+		case err != nil && time.Since(renewed) < l.qm.defaultLockTTL:
+			l.qm.log.Warn("Failed to renew lock", "key", l.key, "error", err)
+			continue
+		}
+
+		l.qm.log.Warn("Lost lock", "key", l.key, "token", l.token, "error", err)
+		l.mu.Lock()
+		l.lost = true
+		l.mu.Unlock()
+		l.cancel()
+		return
+	}
+}
+
+// renew extends the lock and the visibility timeout of the job being
+// processed under it, which share the slot of the MR
+func (l *mrLock) renew(c context.Context) (bool, error) {
+	l.mu.Lock()
+	jobData := l.jobData
+	l.mu.Unlock()
+
+	deadline := time.Now().Add(l.qm.visibilityTimeout).UnixMilli()
+	held, err := renewScript.Run(c, l.qm.redis, []string{l.key, l.processingKey},
+		l.token, l.qm.defaultLockTTL.Milliseconds(), jobData, deadline).Int()
+	return held == 1, err
+}
+
+// setJob sets the data of the job whose visibility timeout is extended by the heartbeat
+func (l *mrLock) setJob(jobData string) {
+	l.mu.Lock()
+	l.jobData = jobData
+	l.mu.Unlock()
+}
+
+// held reports whether the lock was not lost so far
+func (l *mrLock) held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.lost
+}
+
+// release stops the heartbeat and deletes the lock unless it was lost
+func (l *mrLock) release(c context.Context) error {
+	close(l.stop)
+	<-l.done
+	l.cancel()
+	if !l.held() {
+		return nil
+	}
+	// Release even when the work under the lock was cancelled
+	return releaseScript.Run(context.WithoutCancel(c), l.qm.redis, []string{l.key}, l.token).Err()
+}
+
+// requeueScript moves jobs from the processing set of an MR back to its
+// queue, if they are still marked and due by ARGV[1]
+var requeueScript = redis.NewScript(`
+local moved = 0
+for i = 3, #ARGV do
+	local deadline = redis.call("ZSCORE", KEYS[1], ARGV[i])
+	if deadline and tonumber(deadline) <= tonumber(ARGV[1]) then
+		redis.call("ZREM", KEYS[1], ARGV[i])
+		redis.call("LPUSH", KEYS[2], ARGV[i])
+		moved = moved + 1
+	end
+end
+if moved > 0 then
+	redis.call("EXPIRE", KEYS[2], ARGV[2])
+end
+return moved
+`)
+
+// requeueProcessing moves the given processing jobs of an MR that are due by
+// dueBy back to its queue, in one step, and returns how many were moved. The
+// queue is indexed first, so a moved job is always found by the dispatcher.
+func (qm *QueueManager) requeueProcessing(c context.Context, projectID, mergeRequestIID string, dueBy int64, jobData ...string) (int, error) {
+	if err := qm.redis.SAdd(c, qm.getIndexKey(), queueMember(projectID, mergeRequestIID)).Err(); err != nil {
+		return 0, err
+	}
+	keys := []string{qm.getProcessingKey(projectID, mergeRequestIID), qm.getQueueKey(projectID, mergeRequestIID)}
+	args := []interface{}{dueBy, int64(queueTTL.Seconds())}
+	for _, data := range jobData {
+		args = append(args, data)
+	}
+	return requeueScript.Run(c, qm.redis, keys, args...).Int()
+}
+
+// requeueJob queues a job marked with jobData again, as it was before it was
+// dequeued, instead of running it
+func (qm *QueueManager) requeueJob(c context.Context, job *WebhookJob, jobData string) error {
+	_, err := qm.requeueProcessing(c, job.ProjectID, job.MergeRequestIID, math.MaxInt64, jobData)
+	return err
+}
+
+// reapStuckJobs requeues the jobs left in processing beyond the visibility
+// timeout: their worker crashed, or lost its lock, without finishing them.
+// Their interrupted attempt counts towards their maximum attempts. A job
+// moves from processing back to its queue in one step, so it is never lost.
+func (qm *QueueManager) reapStuckJobs(c context.Context) error {
+	members, err := qm.redis.SMembers(c, qm.getProcessingIndexKey()).Result()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	reaped := 0
+	for _, member := range members {
+		if reaped >= maxReapedPerSweep {
+			break
+		}
+		projectID, mergeRequestIID, ok := strings.Cut(member, ":")
+		if !ok {
+			qm.log.Warn("Invalid processing index entry", "member", member)
+			if err := qm.redis.SRem(c, qm.getProcessingIndexKey(), member).Err(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		due, err := qm.redis.ZRangeByScore(c, qm.getProcessingKey(projectID, mergeRequestIID), &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(now, 10),
+			Count: int64(maxReapedPerSweep - reaped),
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			if err := qm.unindexProcessing(c, projectID, mergeRequestIID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		n, err := qm.requeueProcessing(c, projectID, mergeRequestIID, now, due...)
+		if err != nil {
+			return fmt.Errorf("failed to requeue stuck jobs: %w", err)
+		}
+		reaped += n
+	}
+	if reaped > 0 {
+		qm.log.Warn("Requeued stuck jobs", "count", reaped)
+		qm.incrementCounterBy(c, statReaped, int64(reaped))
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestLock_RenewExtendsLockAndVisibility(t *testing.T) {
+	c := context.Background()
+	qm, server := newTestQueueManager(t, &Config{DefaultLockTTL: 10 * time.Second, VisibilityTimeout: time.Minute})
+
+	lock, _, err := qm.acquireLock(c, "1", "2")
+	if err != nil || lock == nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+	defer lock.release(c)
+
+	job := &WebhookJob{ID: "job", ProjectID: "1", MergeRequestIID: "2"}
+	marked, err := qm.markJobAsProcessing(c, job, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.setJob(marked)
+	// Let the lock and the visibility timeout almost run out
+	server.FastForward(8 * time.Second)
+	processingKey := qm.getProcessingKey("1", "2")
+	qm.redis.ZAdd(c, processingKey, &redis.Z{Score: 0, Member: marked})
+
+	held, err := lock.renew(c)
+	if err != nil || !held {
+		t.Fatalf("expected the lock to be renewed, got %v (%v)", held, err)
+	}
+	if ttl := server.TTL(qm.getLockKey("1", "2")); ttl != 10*time.Second {
+		t.Errorf("expected the lock TTL to be reset to 10s, got %v", ttl)
+	}
+	deadline, _ := qm.redis.ZScore(c, processingKey, marked).Result()
+	if want := time.Now().Add(50 * time.Second).UnixMilli(); int64(deadline) < want {
+		t.Errorf("expected the visibility deadline to be extended, got %v", deadline)
+	}
+}
+
+func TestLock_StaleTokenCannotRenewOrRelease(t *testing.T) {
+	c := context.Background()
+	qm, server := newTestQueueManager(t, &Config{DefaultLockTTL: 10 * time.Second})
+
+	stale, _, err := qm.acquireLock(c, "1", "2")
+	if err != nil || stale == nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	// The lock expires and is taken by another worker
+	server.FastForward(11 * time.Second)
+	current, _, err := qm.acquireLock(c, "1", "2")
+	if err != nil || current == nil {
+		t.Fatalf("failed to acquire expired lock: %v", err)
+	}
+	defer current.release(c)
+
+	if held, err := stale.renew(c); err != nil || held {
+		t.Errorf("expected renewal with a stale token to fail, got %v (%v)", held, err)
+	}
+	if err := stale.release(c); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	value, err := server.Get(qm.getLockKey("1", "2"))
+	if err != nil || value != strconv.FormatInt(current.token, 10) {
+		t.Errorf("expected the lock to keep the current token %d, got %q (%v)", current.token, value, err)
+	}
+}
+
+func TestDequeueJobs_MarksJobsAsProcessing(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, nil)
+
+	jobID, err := qm.EnqueueWebhook(c, "1", "2", "merge_request", nil)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	jobs, marked, err := qm.dequeueJobs(c, "1", "2")
+	if err != nil || len(jobs) != 1 || jobs[0].ID != jobID {
+		t.Fatalf("expected the queued job, got %v (%v)", jobs, err)
+	}
+
+	// The worker crashes before running the job: it stays in processing
+	if n, _ := qm.redis.LLen(c, qm.getQueueKey("1", "2")).Result(); n != 0 {
+		t.Errorf("expected the queue to be empty, got %d jobs", n)
+	}
+	if member, _ := qm.redis.SIsMember(c, qm.getProcessingIndexKey(), "1:2").Result(); !member {
+		t.Error("expected the MR to be in the processing index")
+	}
+	processing, _ := qm.redis.ZRange(c, qm.getProcessingKey("1", "2"), 0, -1).Result()
+	if len(processing) != 1 || processing[0] != marked[0] {
+		t.Fatalf("expected the dequeued job in processing, got %v", processing)
+	}
+
+	// and is requeued once its visibility timeout passed
+	qm.redis.ZAdd(c, qm.getProcessingKey("1", "2"), &redis.Z{Score: 0, Member: marked[0]})
+	if err := qm.reapStuckJobs(c); err != nil {
+		t.Fatalf("reap failed: %v", err)
+	}
+	jobs, _, err = qm.dequeueJobs(c, "1", "2")
+	if err != nil || len(jobs) != 1 || jobs[0].ID != jobID {
+		t.Errorf("expected the job to be requeued, got %v (%v)", jobs, err)
+	}
+}
+
+func TestReapStuckJobs_RequeuesExpiredJobsOnly(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, nil)
+
+	stuck := &WebhookJob{ID: "stuck", ProjectID: "1", MergeRequestIID: "2", AttemptedAt: []int64{1}}
+	running := &WebhookJob{ID: "running", ProjectID: "1", MergeRequestIID: "3", AttemptedAt: []int64{1}}
+	for _, job := range []*WebhookJob{stuck, running} {
+		data, _ := json.Marshal(job)
+		if err := qm.queueJobData(c, job, string(data), false); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := qm.dequeueJobs(c, job.ProjectID, job.MergeRequestIID); err != nil {
+			t.Fatal(err)
+		}
+		qm.unindexQueue(c, job.ProjectID, job.MergeRequestIID)
+	}
+	stuckData, _ := qm.redis.ZRange(c, qm.getProcessingKey("1", "2"), 0, -1).Result()
+	qm.redis.ZAdd(c, qm.getProcessingKey("1", "2"), &redis.Z{Score: float64(time.Now().Add(-time.Second).UnixMilli()), Member: stuckData[0]})
+
+	if err := qm.reapStuckJobs(c); err != nil {
+		t.Fatalf("reap failed: %v", err)
+	}
+
+	if member, _ := qm.redis.SIsMember(c, qm.getIndexKey(), "1:2").Result(); !member {
+		t.Error("expected the MR of the stuck job to be indexed")
+	}
+	jobs, _, err := qm.dequeueJobs(c, "1", "2")
+	if err != nil || len(jobs) != 1 || jobs[0].ID != stuck.ID {
+		t.Fatalf("expected the stuck job to be requeued, got %v (%v)", jobs, err)
+	}
+	if n, _ := qm.redis.LLen(c, qm.getQueueKey("1", "3")).Result(); n != 0 {
+		t.Error("expected the running job not to be requeued")
+	}
+	if n, _ := qm.redis.ZCard(c, qm.getProcessingKey("1", "3")).Result(); n != 1 {
+		t.Errorf("expected the running job to stay in processing, got %d jobs", n)
+	}
+	if reaped, _ := qm.getCounter(c, statReaped); reaped != 1 {
+		t.Errorf("expected 1 reaped job, got %d", reaped)
+	}
+}
+
+func TestReapStuckJobs_UnindexesMRsWithoutProcessingJobs(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, nil)
+
+	qm.redis.SAdd(c, qm.getProcessingIndexKey(), "1:2")
+	if err := qm.reapStuckJobs(c); err != nil {
+		t.Fatalf("reap failed: %v", err)
+	}
+	if member, _ := qm.redis.SIsMember(c, qm.getProcessingIndexKey(), "1:2").Result(); member {
+		t.Error("expected the MR without processing jobs to be unindexed")
+	}
+}
+
+// lockLosingProcessor loses the lock of the MR while processing a job
+type lockLosingProcessor struct {
+	lock *mrLock
+	err  error
+}
+
+func (p *lockLosingProcessor) ProcessJob(c context.Context, job *WebhookJob) error {
+	p.lock.mu.Lock()
+	p.lock.lost = true
+	p.lock.mu.Unlock()
+	return p.err
+}
+
+func TestProcessJob_LostLock(t *testing.T) {
+	for name, tt := range map[string]struct {
+		err        error
+		processing bool
+	}{
+		"success is finished":       {err: nil, processing: false},
+		"failure is left to reaper": {err: errInterrupted, processing: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := context.Background()
+			qm, _ := newTestQueueManager(t, nil)
+
+			lock, lockCtx, err := qm.acquireLock(c, "1", "2")
+			if err != nil || lock == nil {
+				t.Fatalf("failed to acquire lock: %v", err)
+			}
+			defer lock.release(c)
+
+			job := &WebhookJob{ID: "job", ProjectID: "1", MergeRequestIID: "2", MaxAttempts: 3}
+			qm.processJob(lockCtx, lock, job, "", &lockLosingProcessor{lock: lock, err: tt.err})
+
+			n, _ := qm.redis.ZCard(c, qm.getProcessingKey("1", "2")).Result()
+			if (n > 0) != tt.processing {
+				t.Errorf("expected job in processing %v, got %d jobs", tt.processing, n)
+			}
+			if retries, _ := qm.redis.ZCard(c, qm.getRetryKey()).Result(); retries != 0 {
+				t.Error("expected no retry to be scheduled without the lock")
+			}
+		})
+	}
+}
+
+// failingMarks fails the transactions that mark a job as processing
+type failingMarks struct {
+	commandRecorder
+}
+
+func (f *failingMarks) BeforeProcessPipeline(c context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if cmd.Name() == "zadd" {
+			return c, errors.New("connection reset")
+		}
+	}
+	return c, nil
+}
+
+func TestProcessJob_RequeuesJobThatCannotBeMarked(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, nil)
+
+	jobID, err := qm.EnqueueWebhook(c, "1", "2", "merge_request", nil)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	jobs, marked, err := qm.dequeueJobs(c, "1", "2")
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("expected the queued job, got %v (%v)", jobs, err)
+	}
+	lock, lockCtx, err := qm.acquireLock(c, "1", "2")
+	if err != nil || lock == nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+	defer lock.release(c)
+
+	qm.redis.AddHook(&failingMarks{})
+	processor := newRecordingProcessor(nil)
+	qm.processJob(lockCtx, lock, jobs[0], marked[0], processor)
+
+	if len(processor.jobs) != 0 {
+		t.Error("expected the job not to run without being marked")
+	}
+	if n, _ := qm.redis.ZCard(c, qm.getProcessingKey("1", "2")).Result(); n != 0 {
+		t.Errorf("expected the job to leave processing, got %d jobs", n)
+	}
+	requeued, _ := qm.redis.LRange(c, qm.getQueueKey("1", "2"), 0, -1).Result()
+	if len(requeued) != 1 || requeued[0] != marked[0] {
+		t.Errorf("expected job %s to be requeued as dequeued, got %v", jobID, requeued)
+	}
+}
@@ -0,0 +1,746 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/tracing"
+	"gitlab-mr-conformity-bot/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+)
+
+// MemoryConfig holds configuration for the in-process queue
+type MemoryConfig struct {
+	// MaxJobs bounds the queued and delayed jobs; enqueueing beyond it fails with ErrQueueFull
+	MaxJobs int
+	// JournalPath is the file jobs are journaled to, so they survive a
+	// restart; jobs are kept in memory only when empty
+	JournalPath     string
+	Debounce        time.Duration
+	MaxRetries      int
+	RetryBackoffMin time.Duration
+	RetryBackoffMax time.Duration
+	MaxDeadJobs     int
+	Workers         int
+	// DrainTimeout bounds how long Close waits for jobs in flight before cancelling them
+	DrainTimeout time.Duration
+}
+
+// MemoryQueue is a JobQueue within a single process, for deployments without
+// Redis. It offers the semantics of QueueManager: jobs of one MR are
+// serialized and coalesced, failed jobs are retried with backoff and end up
+// in a dead-letter queue.
+type MemoryQueue struct {
+	maxJobs         int
+	debounce        time.Duration
+	maxRetries      int
+	retryBackoffMin time.Duration
+	retryBackoffMax time.Duration
+	maxDeadJobs     int
+	workers         int
+	drainTimeout    time.Duration
+	log             *logger.Logger
+
+	mu sync.Mutex
+	// queues holds the queued jobs of every MR, newest first
+	queues map[string][]*WebhookJob
+	// settleUntil holds the end of the debounce window of every MR
+	settleUntil map[string]time.Time
+	// inFlight holds the MRs handed to a worker, with the job being processed
+	inFlight map[string]*WebhookJob
+	delayed  []delayedJob
+	// dead holds dead jobs by ID, deadOrder their IDs oldest first
+	dead      map[string]*DeadJob
+	deadOrder []string
+	queued    int
+	retries   int64
+	deadJobs  int64
+	coalesced int64
+	journal   *journal
+
+	// wake is signalled when jobs may have become ready
+	wake         chan struct{}
+	isProcessing bool
+	stopChan     chan struct{}
+	wg           sync.WaitGroup
+	cancelJobs   context.CancelFunc
+}
+
+// delayedJob is a failed job waiting for its retry
+type delayedJob struct {
+	job *WebhookJob
+	due time.Time
+}
+
+// NewMemoryQueue creates an in-process queue, recovering the jobs of the journal if configured
+func NewMemoryQueue(config *MemoryConfig, log *logger.Logger) (*MemoryQueue, error) {
+	if config == nil {
+		config = &MemoryConfig{}
+	}
+
+	// Set defaults
+	if config.MaxJobs <= 0 {
+		config.MaxJobs = 1000
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.RetryBackoffMin == 0 {
+		config.RetryBackoffMin = 5 * time.Second
+	}
+	if config.RetryBackoffMax < config.RetryBackoffMin {
+		config.RetryBackoffMax = max(5*time.Minute, config.RetryBackoffMin)
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.DrainTimeout <= 0 {
+		config.DrainTimeout = 30 * time.Second
+	}
+
+	mq := &MemoryQueue{
+		maxJobs:         config.MaxJobs,
+		debounce:        config.Debounce,
+		maxRetries:      config.MaxRetries,
+		retryBackoffMin: config.RetryBackoffMin,
+		retryBackoffMax: config.RetryBackoffMax,
+		maxDeadJobs:     config.MaxDeadJobs,
+		workers:         config.Workers,
+		drainTimeout:    config.DrainTimeout,
+		log:             log,
+		queues:          make(map[string][]*WebhookJob),
+		settleUntil:     make(map[string]time.Time),
+		inFlight:        make(map[string]*WebhookJob),
+		dead:            make(map[string]*DeadJob),
+		wake:            make(chan struct{}, 1),
+		stopChan:        make(chan struct{}),
+	}
+
+	if config.JournalPath != "" {
+		j, state, err := openJournal(config.JournalPath)
+		if err != nil {
+			return nil, err
+		}
+		mq.journal = j
+		mq.restore(state)
+	}
+
+	return mq, nil
+}
+
+// restore queues the jobs recovered from the journal
+func (mq *MemoryQueue) restore(state *journalState) {
+	for _, record := range state.pending {
+		job := record.Job
+		if record.Due > 0 {
+			mq.delayed = append(mq.delayed, delayedJob{job: job, due: time.UnixMilli(record.Due)})
+			continue
+		}
+		member := queueMember(job.ProjectID, job.MergeRequestIID)
+		mq.queues[member] = append([]*WebhookJob{job}, mq.queues[member]...)
+		mq.queued++
+	}
+	for _, dead := range state.dead {
+		mq.dead[dead.Job.ID] = dead
+		mq.deadOrder = append(mq.deadOrder, dead.Job.ID)
+	}
+
+	if len(state.pending) > 0 || len(state.dead) > 0 {
+		mq.log.Info("Recovered jobs from journal", "jobs", len(state.pending), "deadJobs", len(state.dead))
+	}
+}
+
+// EnqueueWebhook adds a webhook job to the queue for a specific MR
+func (mq *MemoryQueue) EnqueueWebhook(c context.Context, projectID, mergeRequestIID, webhookType string, payload *gitlabapi.MergeEvent) (jobID string, err error) {
+	c, span := tracing.Start(c, "queue.enqueue", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		attribute.String("gitlab.project_id", projectID),
+		attribute.String("gitlab.merge_request_iid", mergeRequestIID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	jobID = uuid.New().String()
+	job := &WebhookJob{
+		ID:              jobID,
+		ProjectID:       projectID,
+		MergeRequestIID: mergeRequestIID,
+		WebhookType:     webhookType,
+		Payload:         payload,
+		CreatedAt:       time.Now().Unix(),
+		Attempts:        0,
+		MaxAttempts:     mq.maxRetries,
+		TraceContext:    tracing.Inject(c),
+	}
+
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	if mq.queued+len(mq.delayed) >= mq.maxJobs {
+		return "", ErrQueueFull
+	}
+	if err := mq.pushJob(job, true); err != nil {
+		return "", err
+	}
+
+	mq.log.Info("Enqueued webhook job", "jobId", jobID, "projectId", projectID, "mrId", mergeRequestIID)
+	return jobID, nil
+}
+
+// pushJob adds a job to the queue of its MR; the caller holds mu. With
+// debounce, the debounce window of the MR is restarted.
+func (mq *MemoryQueue) pushJob(job *WebhookJob, debounce bool) error {
+	if err := mq.record(journalRecord{Op: journalAdd, Job: job}); err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	member := queueMember(job.ProjectID, job.MergeRequestIID)
+	mq.queues[member] = append([]*WebhookJob{job}, mq.queues[member]...)
+	mq.queued++
+	if debounce && mq.debounce > 0 {
+		mq.settleUntil[member] = time.Now().Add(mq.debounce)
+	}
+	mq.signal()
+	return nil
+}
+
+// record appends to the journal, if any; the caller holds mu
+func (mq *MemoryQueue) record(record journalRecord) error {
+	if mq.journal == nil {
+		return nil
+	}
+	if err := mq.journal.append(record); err != nil {
+		return err
+	}
+	if mq.journal.needsCompaction(mq.liveJobs()) {
+		if err := mq.journal.rewrite(mq.journalRecords()); err != nil {
+			mq.log.Warn("Failed to compact queue journal", "error", err)
+		}
+	}
+	return nil
+}
+
+// forget removes a finished or superseded job from the journal; the caller holds mu
+func (mq *MemoryQueue) forget(job *WebhookJob) {
+	if err := mq.record(journalRecord{Op: journalRemove, ID: job.ID}); err != nil {
+		mq.log.Warn("Failed to journal finished job", "jobId", job.ID, "error", err)
+	}
+}
+
+func (mq *MemoryQueue) liveJobs() int {
+	return mq.queued + len(mq.delayed) + len(mq.inFlight) + len(mq.dead)
+}
+
+// journalRecords returns the records recreating the current jobs; the caller
+// holds mu. Dead jobs come first, so the add record of a job being replayed
+// supersedes its dead record.
+func (mq *MemoryQueue) journalRecords() []journalRecord {
+	var records []journalRecord
+	for _, id := range mq.deadOrder {
+		records = append(records, journalRecord{Op: journalDead, Dead: mq.dead[id]})
+	}
+	for _, jobs := range mq.queues {
+		for _, job := range jobs {
+			records = append(records, journalRecord{Op: journalAdd, Job: job})
+		}
+	}
+	for _, job := range mq.inFlight {
+		if job != nil {
+			records = append(records, journalRecord{Op: journalAdd, Job: job})
+		}
+	}
+	for _, delayed := range mq.delayed {
+		records = append(records, journalRecord{Op: journalAdd, Job: delayed.job, Due: delayed.due.UnixMilli()})
+	}
+	return records
+}
+
+// signal wakes up the dispatcher
+func (mq *MemoryQueue) signal() {
+	select {
+	case mq.wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartProcessor starts a dispatcher handing MRs with ready jobs to a pool of
+// workers. Cancelling c stops dispatching; jobs in flight are only cancelled
+// by StopProcessor once its drain deadline passes.
+func (mq *MemoryQueue) StartProcessor(c context.Context, processor JobProcessor) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.isProcessing {
+		mq.log.Info("Queue processor is already running")
+		return
+	}
+
+	mq.isProcessing = true
+	mq.log.Info("Starting in-process queue processor", "workers", mq.workers)
+
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(c))
+	mq.cancelJobs = cancelJobs
+
+	work := make(chan string)
+	for i := 0; i < mq.workers; i++ {
+		mq.wg.Add(1)
+		go func() {
+			defer mq.wg.Done()
+			for member := range work {
+				mq.processMRQueue(jobCtx, member, processor)
+			}
+		}()
+	}
+
+	mq.wg.Add(1)
+	go func() {
+		defer func() {
+			close(work)
+			mq.wg.Done()
+		}()
+		mq.runDispatcher(c, work)
+	}()
+}
+
+func (mq *MemoryQueue) runDispatcher(c context.Context, work chan<- string) {
+	for {
+		ready, next := mq.readyQueues(time.Now())
+		for _, member := range ready {
+			select {
+			case work <- member:
+			case <-c.Done():
+				return
+			case <-mq.stopChan:
+				return
+			}
+		}
+
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+		select {
+		case <-c.Done():
+		case <-mq.stopChan:
+		case <-mq.wake:
+		case <-due:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if c.Err() != nil || mq.stopping() {
+			return
+		}
+	}
+}
+
+// readyQueues queues the retries that are due and claims the MRs whose jobs
+// can be processed now. It also returns when the next MR becomes ready.
+func (mq *MemoryQueue) readyQueues(now time.Time) ([]string, time.Time) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	var next time.Time
+	later := func(t time.Time) {
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+
+	remaining := mq.delayed[:0]
+	for _, delayed := range mq.delayed {
+		if delayed.due.After(now) {
+			remaining = append(remaining, delayed)
+			later(delayed.due)
+			continue
+		}
+		member := queueMember(delayed.job.ProjectID, delayed.job.MergeRequestIID)
+		mq.queues[member] = append([]*WebhookJob{delayed.job}, mq.queues[member]...)
+		mq.queued++
+		// Keep the journal entry of the job, without its due time
+		if err := mq.record(journalRecord{Op: journalAdd, Job: delayed.job}); err != nil {
+			mq.log.Warn("Failed to journal due retry", "jobId", delayed.job.ID, "error", err)
+		}
+	}
+	mq.delayed = remaining
+
+	var ready []string
+	for member, jobs := range mq.queues {
+		if len(jobs) == 0 {
+			delete(mq.queues, member)
+			continue
+		}
+		if _, busy := mq.inFlight[member]; busy {
+			continue
+		}
+		if until, ok := mq.settleUntil[member]; ok {
+			if until.After(now) {
+				later(until)
+				continue
+			}
+			delete(mq.settleUntil, member)
+		}
+		mq.inFlight[member] = nil
+		ready = append(ready, member)
+	}
+	sort.Strings(ready)
+	return ready, next
+}
+
+// processMRQueue processes the queued jobs of a claimed MR, coalescing jobs
+// queued together, until its queue is empty, new events arrive or the
+// processor stops
+func (mq *MemoryQueue) processMRQueue(c context.Context, member string, processor JobProcessor) {
+	defer func() {
+		mq.mu.Lock()
+		delete(mq.inFlight, member)
+		mq.mu.Unlock()
+		mq.signal()
+	}()
+
+	for {
+		mq.mu.Lock()
+		jobs := mq.queues[member]
+		delete(mq.queues, member)
+		mq.queued -= len(jobs)
+		if len(jobs) == 0 {
+			mq.mu.Unlock()
+			return
+		}
+
+		job := latestJob(jobs)
+		for _, superseded := range jobs {
+			if superseded != job {
+				mq.forget(superseded)
+			}
+		}
+		mq.coalesced += int64(len(jobs) - 1)
+		mq.inFlight[member] = job
+		mq.mu.Unlock()
+
+		if len(jobs) > 1 {
+			mq.log.Info("Coalesced queued jobs", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "jobs", len(jobs))
+		}
+		mq.processJob(c, job, processor)
+
+		mq.mu.Lock()
+		mq.inFlight[member] = nil
+		settling := mq.settleUntil[member].After(time.Now())
+		mq.mu.Unlock()
+
+		// Leave the remaining jobs queued while draining or settling
+		if mq.stopping() || settling {
+			return
+		}
+	}
+}
+
+// processJob runs a job; it stays journaled until it finished, so an attempt
+// interrupted by a crash is detected after a restart. The job is in inFlight,
+// where compaction of the journal reads it, so it is only changed under mu.
+func (mq *MemoryQueue) processJob(c context.Context, job *WebhookJob, processor JobProcessor) {
+	c, span := startJobSpan(c, job)
+	defer span.End()
+
+	if len(job.AttemptedAt) > job.Attempts {
+		mq.mu.Lock()
+		job.Attempts = len(job.AttemptedAt) - 1
+		mq.mu.Unlock()
+		mq.log.Warn("Previous attempt of job was interrupted", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "attempt", job.Attempts+1)
+		mq.handleJobFailure(c, job, errInterrupted, processor)
+		return
+	}
+
+	mq.log.Info("Processing job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID)
+
+	mq.mu.Lock()
+	job.AttemptedAt = append(job.AttemptedAt, time.Now().Unix())
+	if err := mq.record(journalRecord{Op: journalAdd, Job: job}); err != nil {
+		mq.log.Warn("Failed to journal job attempt", "jobId", job.ID, "error", err)
+	}
+	mq.mu.Unlock()
+
+	if err := processor.ProcessJob(c, job); err != nil {
+		tracing.RecordError(span, err)
+		mq.log.Error("Error processing job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+		mq.handleJobFailure(c, job, err, processor)
+		return
+	}
+
+	mq.log.Info("Successfully processed job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID)
+	mq.mu.Lock()
+	mq.forget(job)
+	mq.mu.Unlock()
+}
+
+func (mq *MemoryQueue) handleJobFailure(c context.Context, job *WebhookJob, jobErr error, processor JobProcessor) {
+	mq.mu.Lock()
+	job.Attempts++
+	job.LastError = jobErr.Error()
+	mq.mu.Unlock()
+
+	switch {
+	case IsPermanent(jobErr):
+		mq.log.Info("Job failed with a non-retryable error", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "attempt", job.Attempts, "error", jobErr)
+	case job.Attempts < job.MaxAttempts:
+		// Schedule the job for retry once its backoff passed
+		delay := retryDelay(mq.retryBackoffMin, mq.retryBackoffMax, job.Attempts)
+		mq.log.Info("Retrying job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "attempt", job.Attempts, "maxAttempts", job.MaxAttempts, "delay", delay)
+
+		mq.mu.Lock()
+		due := time.Now().Add(delay)
+		if err := mq.record(journalRecord{Op: journalAdd, Job: job, Due: due.UnixMilli()}); err != nil {
+			mq.log.Warn("Failed to journal retry", "jobId", job.ID, "error", err)
+		}
+		mq.delayed = append(mq.delayed, delayedJob{job: job, due: due})
+		mq.retries++
+		mq.mu.Unlock()
+		mq.signal()
+		return
+	default:
+		mq.log.Info("Job failed after max attempts", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "maxAttempts", job.MaxAttempts, "error", jobErr)
+	}
+
+	// Move the job to the dead-letter queue
+	mq.mu.Lock()
+	mq.addDeadJob(job)
+	mq.deadJobs++
+	mq.mu.Unlock()
+
+	if handler, ok := processor.(DeadJobHandler); ok {
+		handler.HandleDeadJob(c, job)
+	}
+}
+
+// addDeadJob adds a job to the dead-letter queue, dropping the oldest dead
+// jobs beyond maxDeadJobs; the caller holds mu
+func (mq *MemoryQueue) addDeadJob(job *WebhookJob) {
+	dead := &DeadJob{Job: job, Error: job.LastError, FailedAt: time.Now().Unix()}
+	if err := mq.record(journalRecord{Op: journalDead, Dead: dead}); err != nil {
+		mq.log.Warn("Failed to journal dead job", "jobId", job.ID, "error", err)
+	}
+	mq.dead[job.ID] = dead
+	mq.deadOrder = append(mq.deadOrder, job.ID)
+
+	for mq.maxDeadJobs > 0 && len(mq.deadOrder) > mq.maxDeadJobs {
+		oldest := mq.deadOrder[0]
+		mq.deadOrder = mq.deadOrder[1:]
+		mq.removeDeadJob(oldest)
+	}
+}
+
+// removeDeadJob removes a dead job by ID, except from deadOrder; the caller holds mu
+func (mq *MemoryQueue) removeDeadJob(jobID string) {
+	delete(mq.dead, jobID)
+	if err := mq.record(journalRecord{Op: journalRemove, ID: jobID}); err != nil {
+		mq.log.Warn("Failed to journal removed dead job", "jobId", jobID, "error", err)
+	}
+}
+
+// StopProcessor stops dispatching and waits for the workers to finish their
+// current job; queued jobs are kept in the journal for the next start, if any.
+// When c is done first, jobs in flight are cancelled and c's error returned.
+func (mq *MemoryQueue) StopProcessor(c context.Context) error {
+	mq.mu.Lock()
+	if !mq.isProcessing {
+		mq.mu.Unlock()
+		return nil
+	}
+	mq.isProcessing = false
+	mq.log.Info("Stopping in-process queue processor")
+	close(mq.stopChan)
+	mq.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		mq.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-c.Done():
+		err = c.Err()
+		mq.log.Warn("Cancelling jobs in flight", "error", err)
+		mq.cancelJobs()
+		<-done
+	}
+	mq.cancelJobs()
+
+	mq.mu.Lock()
+	if left := mq.queued + len(mq.delayed); left > 0 && mq.journal == nil {
+		mq.log.Warn("Dropping queued jobs, configure a journal to keep them across restarts", "jobs", left)
+	}
+	mq.mu.Unlock()
+
+	mq.log.Info("Queue processor stopped")
+	return err
+}
+
+// stopping reports whether StopProcessor was called
+func (mq *MemoryQueue) stopping() bool {
+	select {
+	case <-mq.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetQueueStats returns statistics about the queues
+func (mq *MemoryQueue) GetQueueStats(c context.Context) (*QueueStats, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	stats := &QueueStats{
+		TotalJobs:   mq.queued,
+		DelayedJobs: len(mq.delayed),
+		Retries:     mq.retries,
+		DeadJobs:    mq.deadJobs,
+		Coalesced:   mq.coalesced,
+	}
+	for _, job := range mq.inFlight {
+		if job != nil {
+			stats.ProcessingJobs++
+		}
+	}
+	for member, jobs := range mq.queues {
+		if len(jobs) == 0 {
+			continue
+		}
+		projectID, mergeRequestIID, _ := strings.Cut(member, ":")
+		stats.QueueDetails = append(stats.QueueDetails, QueueDetail{
+			ProjectID:       projectID,
+			MergeRequestIID: mergeRequestIID,
+			JobCount:        len(jobs),
+		})
+	}
+	sort.Slice(stats.QueueDetails, func(i, j int) bool {
+		a, b := stats.QueueDetails[i], stats.QueueDetails[j]
+		return a.ProjectID+":"+a.MergeRequestIID < b.ProjectID+":"+b.MergeRequestIID
+	})
+	stats.TotalQueues = len(stats.QueueDetails)
+	return stats, nil
+}
+
+// Health reports whether the queue can take jobs
+func (mq *MemoryQueue) Health(c context.Context) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.queued+len(mq.delayed) >= mq.maxJobs {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// ListDeadJobs returns dead jobs, most recent first, and the total number of dead jobs
+func (mq *MemoryQueue) ListDeadJobs(c context.Context, offset, limit int) ([]*DeadJob, int64, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	jobs := []*DeadJob{}
+	for i := len(mq.deadOrder) - 1 - offset; i >= 0 && len(jobs) < limit; i-- {
+		jobs = append(jobs, mq.dead[mq.deadOrder[i]])
+	}
+	return jobs, int64(len(mq.deadOrder)), nil
+}
+
+// GetDeadJob returns a dead job by ID
+func (mq *MemoryQueue) GetDeadJob(c context.Context, jobID string) (*DeadJob, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	dead, ok := mq.dead[jobID]
+	if !ok {
+		return nil, ErrDeadJobNotFound
+	}
+	return dead, nil
+}
+
+// ReplayDeadJob queues a dead job again with a fresh set of attempts, then
+// removes it from the dead-letter queue
+func (mq *MemoryQueue) ReplayDeadJob(c context.Context, jobID string) (*WebhookJob, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	dead, ok := mq.dead[jobID]
+	if !ok {
+		return nil, ErrDeadJobNotFound
+	}
+	if mq.queued+len(mq.delayed) >= mq.maxJobs {
+		return nil, ErrQueueFull
+	}
+
+	job := *dead.Job
+	job.Attempts = 0
+	job.MaxAttempts = mq.maxRetries
+	job.AttemptedAt = nil
+	job.LastError = ""
+	job.CreatedAt = time.Now().Unix()
+	if err := mq.pushJob(&job, false); err != nil {
+		return nil, err
+	}
+	// The add record of the job supersedes its dead record
+	mq.dropFromDeadOrder(jobID)
+	delete(mq.dead, jobID)
+
+	mq.log.Info("Replayed dead job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID)
+	return &job, nil
+}
+
+// DeleteDeadJob removes a job from the dead-letter queue and reports whether it was there
+func (mq *MemoryQueue) DeleteDeadJob(c context.Context, jobID string) (bool, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	if _, ok := mq.dead[jobID]; !ok {
+		return false, nil
+	}
+	mq.dropFromDeadOrder(jobID)
+	mq.removeDeadJob(jobID)
+	return true, nil
+}
+
+// PurgeDeadJobs empties the dead-letter queue and returns the number of removed jobs
+func (mq *MemoryQueue) PurgeDeadJobs(c context.Context) (int64, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	count := int64(len(mq.deadOrder))
+	for _, id := range mq.deadOrder {
+		mq.removeDeadJob(id)
+	}
+	mq.deadOrder = nil
+	return count, nil
+}
+
+func (mq *MemoryQueue) dropFromDeadOrder(jobID string) {
+	for i, id := range mq.deadOrder {
+		if id == jobID {
+			mq.deadOrder = append(mq.deadOrder[:i], mq.deadOrder[i+1:]...)
+			return
+		}
+	}
+}
+
+// Close stops the processor, after jobs in flight finished or were cancelled
+// at the drain timeout, and closes the journal
+func (mq *MemoryQueue) Close() error {
+	c, cancel := context.WithTimeout(context.Background(), mq.drainTimeout)
+	defer cancel()
+	err := mq.StopProcessor(c)
+	if mq.journal != nil {
+		err = errors.Join(err, mq.journal.close())
+	}
+	return err
+}
@@ -0,0 +1,285 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab-mr-conformity-bot/pkg/logger"
+)
+
+// recordingProcessor records processed jobs and fails them with err
+type recordingProcessor struct {
+	mu   sync.Mutex
+	jobs []*WebhookJob
+	dead []*WebhookJob
+	err  error
+	done chan struct{}
+}
+
+func newRecordingProcessor(err error) *recordingProcessor {
+	return &recordingProcessor{err: err, done: make(chan struct{}, 100)}
+}
+
+func (p *recordingProcessor) ProcessJob(c context.Context, job *WebhookJob) error {
+	p.mu.Lock()
+	p.jobs = append(p.jobs, job)
+	p.mu.Unlock()
+	p.done <- struct{}{}
+	return p.err
+}
+
+func (p *recordingProcessor) HandleDeadJob(c context.Context, job *WebhookJob) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dead = append(p.dead, job)
+}
+
+func (p *recordingProcessor) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a job to be processed")
+	}
+}
+
+func newTestMemoryQueue(t *testing.T, config *MemoryConfig) *MemoryQueue {
+	t.Helper()
+	mq, err := NewMemoryQueue(config, logger.New())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	t.Cleanup(func() { mq.Close() })
+	return mq
+}
+
+func TestMemoryQueue_CoalescesJobsOfMR(t *testing.T) {
+	c := context.Background()
+	mq := newTestMemoryQueue(t, &MemoryConfig{})
+
+	for range 3 {
+		if _, err := mq.EnqueueWebhook(c, "1", "2", "merge_request", nil); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	}
+	latest, err := mq.EnqueueWebhook(c, "1", "2", "merge_request", nil)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	// Same timestamp: the last queued job wins
+	mq.mu.Lock()
+	for _, job := range mq.queues[queueMember("1", "2")] {
+		job.CreatedAt = 100
+	}
+	mq.mu.Unlock()
+
+	processor := newRecordingProcessor(nil)
+	mq.StartProcessor(c, processor)
+	processor.wait(t)
+	if err := mq.StopProcessor(c); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	if len(processor.jobs) != 1 || processor.jobs[0].ID != latest {
+		t.Fatalf("expected only the latest job to be processed, got %d jobs", len(processor.jobs))
+	}
+	stats, _ := mq.GetQueueStats(c)
+	if stats.Coalesced != 3 || stats.TotalJobs != 0 {
+		t.Errorf("expected 3 coalesced and no queued jobs, got %+v", stats)
+	}
+}
+
+func TestMemoryQueue_PermanentErrorIsDead(t *testing.T) {
+	c := context.Background()
+	mq := newTestMemoryQueue(t, &MemoryConfig{MaxRetries: 3})
+
+	jobID, err := mq.EnqueueWebhook(c, "1", "2", "merge_request", nil)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	processor := newRecordingProcessor(Permanent(errors.New("merge request not found")))
+	mq.StartProcessor(c, processor)
+	processor.wait(t)
+	if err := mq.StopProcessor(c); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	dead, err := mq.GetDeadJob(c, jobID)
+	if err != nil {
+		t.Fatalf("expected a dead job: %v", err)
+	}
+	if dead.Job.Attempts != 1 || dead.Error != "merge request not found" {
+		t.Errorf("unexpected dead job: attempts %d, error %q", dead.Job.Attempts, dead.Error)
+	}
+	if len(processor.dead) != 1 {
+		t.Errorf("expected the dead job handler to be called once, got %d", len(processor.dead))
+	}
+}
+
+func TestMemoryQueue_QueueFull(t *testing.T) {
+	c := context.Background()
+	mq := newTestMemoryQueue(t, &MemoryConfig{MaxJobs: 1})
+
+	if _, err := mq.EnqueueWebhook(c, "1", "2", "merge_request", nil); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if _, err := mq.EnqueueWebhook(c, "1", "3", "merge_request", nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestMemoryQueue_JournalRecoversJobs(t *testing.T) {
+	c := context.Background()
+	path := filepath.Join(t.TempDir(), "queue.journal")
+
+	mq, err := NewMemoryQueue(&MemoryConfig{JournalPath: path, MaxRetries: 1}, logger.New())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	queued, err := mq.EnqueueWebhook(c, "1", "2", "merge_request", nil)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	// A job whose attempt was interrupted by a crash
+	interrupted, err := mq.EnqueueWebhook(c, "1", "3", "merge_request", nil)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	mq.mu.Lock()
+	job := mq.queues[queueMember("1", "3")][0]
+	job.AttemptedAt = append(job.AttemptedAt, time.Now().Unix())
+	if err := mq.record(journalRecord{Op: journalAdd, Job: job}); err != nil {
+		t.Fatalf("journal failed: %v", err)
+	}
+	mq.mu.Unlock()
+	if err := mq.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	mq = newTestMemoryQueue(t, &MemoryConfig{JournalPath: path})
+	stats, _ := mq.GetQueueStats(c)
+	if stats.TotalJobs != 2 {
+		t.Fatalf("expected 2 recovered jobs, got %d", stats.TotalJobs)
+	}
+
+	processor := newRecordingProcessor(nil)
+	mq.StartProcessor(c, processor)
+	processor.wait(t)
+	// The interrupted attempt was its last one
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := mq.GetDeadJob(c, interrupted); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("expected the interrupted job to be dead: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := mq.StopProcessor(c); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	if len(processor.jobs) != 1 || processor.jobs[0].ID != queued {
+		t.Fatalf("expected only the queued job to be processed, got %d jobs", len(processor.jobs))
+	}
+}
+
+func TestMemoryQueue_CompactsWhileProcessing(t *testing.T) {
+	c := context.Background()
+	mq := newTestMemoryQueue(t, &MemoryConfig{
+		JournalPath:     filepath.Join(t.TempDir(), "queue.journal"),
+		MaxRetries:      20,
+		RetryBackoffMin: time.Millisecond,
+		RetryBackoffMax: time.Millisecond,
+	})
+	jobID, err := mq.EnqueueWebhook(c, "1", "2", "merge_request", nil)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	// Compact the journal continuously while the job is attempted
+	stop := make(chan struct{})
+	compacted := make(chan struct{})
+	go func() {
+		defer close(compacted)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			mq.mu.Lock()
+			if err := mq.journal.rewrite(mq.journalRecords()); err != nil {
+				t.Errorf("compaction failed: %v", err)
+			}
+			mq.mu.Unlock()
+		}
+	}()
+
+	processor := newRecordingProcessor(errors.New("boom"))
+	mq.StartProcessor(c, processor)
+	for range 20 {
+		processor.wait(t)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := mq.GetDeadJob(c, jobID); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("expected the job to be dead: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	<-compacted
+}
+
+func TestMemoryQueue_ReplayedJobSurvivesCompaction(t *testing.T) {
+	c := context.Background()
+	path := filepath.Join(t.TempDir(), "queue.journal")
+
+	mq, err := NewMemoryQueue(&MemoryConfig{JournalPath: path}, logger.New())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	mq.mu.Lock()
+	mq.addDeadJob(&WebhookJob{ID: "dead", ProjectID: "1", MergeRequestIID: "2", Attempts: 3, LastError: "boom"})
+	mq.mu.Unlock()
+
+	job, err := mq.ReplayDeadJob(c, "dead")
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if job.Attempts != 0 || job.LastError != "" {
+		t.Errorf("expected a fresh set of attempts, got %+v", job)
+	}
+	if _, err := mq.GetDeadJob(c, "dead"); !errors.Is(err, ErrDeadJobNotFound) {
+		t.Errorf("expected the replayed job to leave the dead-letter queue, got %v", err)
+	}
+
+	// Compaction between the add record and the removal of the dead job
+	mq.mu.Lock()
+	mq.dead["dead"] = &DeadJob{Job: &WebhookJob{ID: "dead", ProjectID: "1", MergeRequestIID: "2"}}
+	mq.deadOrder = append(mq.deadOrder, "dead")
+	if err := mq.journal.rewrite(mq.journalRecords()); err != nil {
+		t.Fatalf("compaction failed: %v", err)
+	}
+	mq.mu.Unlock()
+	if err := mq.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	mq = newTestMemoryQueue(t, &MemoryConfig{JournalPath: path})
+	stats, _ := mq.GetQueueStats(c)
+	if stats.TotalJobs != 1 {
+		t.Errorf("expected the replayed job to be recovered as queued, got %d jobs", stats.TotalJobs)
+	}
+	if _, err := mq.GetDeadJob(c, "dead"); !errors.Is(err, ErrDeadJobNotFound) {
+		t.Errorf("expected the replayed job not to be recovered as dead, got %v", err)
+	}
+}
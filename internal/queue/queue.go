@@ -3,13 +3,19 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"gitlab-mr-conformity-bot/internal/redisclient"
+	"gitlab-mr-conformity-bot/internal/tracing"
 	"gitlab-mr-conformity-bot/pkg/logger"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	gitlabapi "gitlab.com/gitlab-org/api/client-go"
 )
@@ -25,6 +31,12 @@ type WebhookJob struct {
 	CreatedAt       int64 //`json:"created_at"`
 	Attempts        int   //`json:"attempts"`
 	MaxAttempts     int   //`json:"max_attempts"`
+	// TraceContext links the processing of the job to the webhook request that queued it
+	TraceContext map[string]string `json:",omitempty"`
+	// AttemptedAt holds the start time of every attempt, as Unix seconds
+	AttemptedAt []int64 `json:",omitempty"`
+	// LastError is the error of the last failed attempt
+	LastError string `json:",omitempty"`
 }
 
 // JobProcessor defines the interface for processing webhook jobs
@@ -32,35 +44,76 @@ type JobProcessor interface {
 	ProcessJob(c context.Context, job *WebhookJob) error
 }
 
+// DeadJobHandler is implemented by processors that act on jobs moved to the
+// dead-letter queue after their last attempt failed
+type DeadJobHandler interface {
+	HandleDeadJob(c context.Context, job *WebhookJob)
+}
+
 // QueueManager manages Redis queues for GitLab MR webhooks
 type QueueManager struct {
-	redis              *redis.Client
+	redis              redis.UniversalClient
 	queuePrefix        string
 	lockPrefix         string
 	processingPrefix   string
+	statsPrefix        string
+	eventPrefix        string
+	deadPrefix         string
+	maxDeadJobs        int64
+	debouncePrefix     string
+	debounce           time.Duration
 	defaultLockTTL     time.Duration
+	visibilityTimeout  time.Duration
 	maxRetries         int
+	retryBackoffMin    time.Duration
+	retryBackoffMax    time.Duration
 	processingInterval time.Duration
+	workers            int
+	drainTimeout       time.Duration
+	mu                 sync.Mutex
 	isProcessing       bool
 	stopChan           chan struct{}
+	wg                 sync.WaitGroup
+	cancelJobs         context.CancelFunc
 	log                *logger.Logger
 }
 
 // Config holds configuration for the queue manager
 type Config struct {
-	RedisHost          string
-	RedisPassword      string
-	RedisDB            int
-	QueuePrefix        string
-	LockPrefix         string
-	ProcessingPrefix   string
-	DefaultLockTTL     time.Duration
-	MaxRetries         int
+	Redis            redisclient.Config
+	QueuePrefix      string
+	LockPrefix       string
+	ProcessingPrefix string
+	// StatsPrefix prefixes the retry and dead job counters shared by all replicas
+	StatsPrefix string
+	// EventPrefix prefixes the IDs of webhook deliveries that were seen
+	EventPrefix string
+	// DeadPrefix prefixes the dead-letter queue of jobs that failed their last attempt
+	DeadPrefix string
+	// MaxDeadJobs caps the dead-letter queue, dropping the oldest jobs; 0 keeps all
+	MaxDeadJobs int
+	// DebouncePrefix prefixes the markers of merge requests that received an event within Debounce
+	DebouncePrefix string
+	// Debounce delays processing a merge request until no event arrived for
+	// this long, so bursts of events are evaluated once; 0 disables it
+	Debounce       time.Duration
+	DefaultLockTTL time.Duration
+	// VisibilityTimeout is how long a job may stay in processing without a
+	// heartbeat before it is requeued; it must exceed DefaultLockTTL
+	VisibilityTimeout time.Duration
+	MaxRetries        int
+	// RetryBackoffMin and RetryBackoffMax bound the exponentially growing delay before a failed job is retried
+	RetryBackoffMin    time.Duration
+	RetryBackoffMax    time.Duration
 	ProcessingInterval time.Duration
+	// Workers is the number of MRs processed in parallel by this replica
+	Workers int
+	// DrainTimeout bounds how long Close waits for jobs in flight before cancelling them
+	DrainTimeout time.Duration
 }
 
 // NewQueueManager creates a new queue manager instance
-func NewQueueManager(config *Config, log *logger.Logger) *QueueManager {
+func NewQueueManager(config *Config, log *logger.Logger) (*QueueManager, error) {
 	if config == nil {
 		config = &Config{}
 	}
@@ -75,38 +128,81 @@ func NewQueueManager(config *Config, log *logger.Logger) *QueueManager {
 	if config.ProcessingPrefix == "" {
 		config.ProcessingPrefix = "gitlab:mr:processing"
 	}
+	if config.StatsPrefix == "" {
+		config.StatsPrefix = "gitlab:mr:stats"
+	}
+	if config.EventPrefix == "" {
+		config.EventPrefix = "gitlab:mr:event"
+	}
+	if config.DeadPrefix == "" {
+		config.DeadPrefix = "gitlab:mr:dead"
+	}
+	if config.DebouncePrefix == "" {
+		config.DebouncePrefix = "gitlab:mr:debounce"
+	}
 	if config.DefaultLockTTL == 0 {
 		config.DefaultLockTTL = 5 * time.Minute
 	}
+	if config.VisibilityTimeout <= config.DefaultLockTTL {
+		config.VisibilityTimeout = max(time.Minute, 3*config.DefaultLockTTL)
+	}
 	if config.MaxRetries == 0 {
 		config.MaxRetries = 3
 	}
+	if config.RetryBackoffMin == 0 {
+		config.RetryBackoffMin = 5 * time.Second
+	}
+	if config.RetryBackoffMax < config.RetryBackoffMin {
+		config.RetryBackoffMax = max(5*time.Minute, config.RetryBackoffMin)
+	}
 	if config.ProcessingInterval == 0 {
 		config.ProcessingInterval = 1 * time.Second
 	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.DrainTimeout <= 0 {
+		config.DrainTimeout = 30 * time.Second
+	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     config.RedisHost,
-		Password: config.RedisPassword,
-		DB:       config.RedisDB,
-	})
+	rdb, err := redisclient.New(config.Redis)
+	if err != nil {
+		return nil, err
+	}
 
 	return &QueueManager{
 		redis:              rdb,
 		queuePrefix:        config.QueuePrefix,
 		lockPrefix:         config.LockPrefix,
 		processingPrefix:   config.ProcessingPrefix,
+		statsPrefix:        config.StatsPrefix,
+		eventPrefix:        config.EventPrefix,
+		deadPrefix:         config.DeadPrefix,
+		maxDeadJobs:        int64(config.MaxDeadJobs),
+		debouncePrefix:     config.DebouncePrefix,
+		debounce:           config.Debounce,
 		defaultLockTTL:     config.DefaultLockTTL,
+		visibilityTimeout:  config.VisibilityTimeout,
 		maxRetries:         config.MaxRetries,
+		retryBackoffMin:    config.RetryBackoffMin,
+		retryBackoffMax:    config.RetryBackoffMax,
 		processingInterval: config.ProcessingInterval,
+		workers:            config.Workers,
+		drainTimeout:       config.DrainTimeout,
 		stopChan:           make(chan struct{}),
 		log:                log,
-	}
+	}, nil
 }
 
 // EnqueueWebhook adds a webhook job to the queue for a specific MR
-func (qm *QueueManager) EnqueueWebhook(c context.Context, projectID, mergeRequestIID, webhookType string, payload *gitlabapi.MergeEvent) (string, error) {
-	jobID := uuid.New().String()
+func (qm *QueueManager) EnqueueWebhook(c context.Context, projectID, mergeRequestIID, webhookType string, payload *gitlabapi.MergeEvent) (jobID string, err error) {
+	c, span := tracing.Start(c, "queue.enqueue", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		attribute.String("gitlab.project_id", projectID),
+		attribute.String("gitlab.merge_request_iid", mergeRequestIID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	jobID = uuid.New().String()
 	job := &WebhookJob{
 		ID:              jobID,
 		ProjectID:       projectID,
@@ -116,182 +212,522 @@ func (qm *QueueManager) EnqueueWebhook(c context.Context, projectID, mergeReques
 		CreatedAt:       time.Now().Unix(),
 		Attempts:        0,
 		MaxAttempts:     qm.maxRetries,
+		TraceContext:    tracing.Inject(c),
 	}
 
-	jobData, err := json.Marshal(job)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal job: %w", err)
+	if err := qm.pushJob(c, job, true); err != nil {
+		return "", err
 	}
 
-	queueKey := qm.getQueueKey(projectID, mergeRequestIID)
+	qm.log.Info("Enqueued webhook job", "jobId", jobID, "projectId", projectID, "mrId", mergeRequestIID)
+	return jobID, nil
+}
 
-	// Add job to the MR-specific queue (LPUSH for FIFO with RPOP)
-	if err := qm.redis.LPush(c, queueKey, jobData).Err(); err != nil {
-		return "", fmt.Errorf("failed to enqueue job: %w", err)
+// pushJob adds a job to the queue of its MR and wakes up a processor. With
+// debounce, the debounce window of the MR is restarted.
+func (qm *QueueManager) pushJob(c context.Context, job *WebhookJob, debounce bool) error {
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	if err := qm.queueJobData(c, job, string(jobData), debounce); err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
 	}
 
-	// Set queue expiration (cleanup after 24 hours if not processed)
-	if err := qm.redis.Expire(c, queueKey, 24*time.Hour).Err(); err != nil {
-		qm.log.Warn("Failed to set queue expiration", "error", err)
+	member := queueMember(job.ProjectID, job.MergeRequestIID)
+	// Wake up a waiting processor; every wakeup looks at all queues, so a
+	// short list of pending wakeups is enough
+	if _, err := qm.redis.Pipelined(c, func(pipe redis.Pipeliner) error {
+		pipe.LPush(c, qm.getWakeupKey(), member)
+		pipe.LTrim(c, qm.getWakeupKey(), 0, maxPendingWakeups-1)
+		return nil
+	}); err != nil {
+		qm.log.Warn("Failed to notify queue processors", "error", err)
 	}
 
-	qm.log.Info("Enqueued webhook job", "jobId", jobID, "projectId", projectID, "mrId", mergeRequestIID)
-	return jobID, nil
+	return nil
 }
 
-// ProcessMRQueue processes all queued jobs for a specific MR
+// queueJobData adds an encoded job to the queue of its MR, then indexes the
+// queue. The queue and the index hash to different cluster slots, so they are
+// written one after the other; unindexQueue relies on this order.
+func (qm *QueueManager) queueJobData(c context.Context, job *WebhookJob, jobData string, debounce bool) error {
+	queueKey := qm.getQueueKey(job.ProjectID, job.MergeRequestIID)
+	if _, err := qm.redis.TxPipelined(c, func(pipe redis.Pipeliner) error {
+		pipe.LPush(c, queueKey, jobData)
+		// Set queue expiration (cleanup after 24 hours if not processed)
+		pipe.Expire(c, queueKey, queueTTL)
+		// Every event restarts the debounce window of the MR
+		if debounce && qm.debounce > 0 {
+			pipe.Set(c, qm.getDebounceKey(job.ProjectID, job.MergeRequestIID), time.Now().Unix(), qm.debounce)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return qm.redis.SAdd(c, qm.getIndexKey(), queueMember(job.ProjectID, job.MergeRequestIID)).Err()
+}
+
+// ProcessMRQueue processes the queued jobs of a specific MR. Jobs queued
+// together are coalesced into one, as every check reads the current state of
+// the MR; MRs within their debounce window are left for a later run.
 func (qm *QueueManager) ProcessMRQueue(c context.Context, projectID, mergeRequestIID string, processor JobProcessor) error {
-	queueKey := qm.getQueueKey(projectID, mergeRequestIID)
-	lockKey := qm.getLockKey(projectID, mergeRequestIID)
+	_, err := qm.processMRQueue(c, projectID, mergeRequestIID, processor)
+	return err
+}
+
+// processMRQueue processes the queue of an MR and reports whether jobs are
+// left pending, because of the debounce window or another processor's lock
+func (qm *QueueManager) processMRQueue(c context.Context, projectID, mergeRequestIID string, processor JobProcessor) (pending bool, err error) {
+	c, span := tracing.Start(c, "queue.process_mr", trace.WithAttributes(
+		attribute.String("gitlab.project_id", projectID),
+		attribute.String("gitlab.merge_request_iid", mergeRequestIID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if settling, err := qm.isSettling(c, projectID, mergeRequestIID); err != nil || settling {
+		return settling, err
+	}
 
 	// Try to acquire lock for this MR
-	locked, err := qm.acquireLock(c, lockKey)
+	lock, lockCtx, err := qm.acquireLock(c, projectID, mergeRequestIID)
 	if err != nil {
-		return fmt.Errorf("failed to acquire lock: %w", err)
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
 	}
-	if !locked {
+	if lock == nil {
 		qm.log.Info("MR is already being processed", "projectId", projectID, "mrId", mergeRequestIID)
-		return nil
+		return true, nil
 	}
 
 	defer func() {
-		if err := qm.releaseLock(c, lockKey); err != nil {
+		if err := lock.release(c); err != nil {
 			qm.log.Error("Error releasing lock", "error", err)
 		}
 	}()
 
-	// Process jobs one by one from the queue
+	// Process the queued jobs until the queue is empty or new events arrive
 	for {
-		job, err := qm.dequeueJob(c, queueKey)
+		jobs, marked, err := qm.dequeueJobs(c, projectID, mergeRequestIID)
 		if err != nil {
-			return fmt.Errorf("failed to dequeue job: %w", err)
+			return false, fmt.Errorf("failed to dequeue jobs: %w", err)
 		}
-		if job == nil {
-			break // No more jobs in queue
+		if len(jobs) == 0 {
+			// No more jobs in queue
+			if err := qm.unindexQueue(c, projectID, mergeRequestIID); err != nil {
+				return false, fmt.Errorf("failed to unindex queue: %w", err)
+			}
+			if err := qm.unindexProcessing(c, projectID, mergeRequestIID); err != nil {
+				return false, fmt.Errorf("failed to unindex processing jobs: %w", err)
+			}
+			return false, nil
 		}
 
-		qm.log.Info("Processing job", "jobId", job.ID, "projectId", projectID, "mrId", mergeRequestIID)
+		job := latestJob(jobs)
+		var jobData string
+		for i := range jobs {
+			if jobs[i] == job {
+				jobData = marked[i]
+				continue
+			}
+			// A coalesced job is done with the job it was coalesced into
+			if err := qm.removeJobFromProcessing(c, jobs[i], marked[i]); err != nil {
+				qm.log.Warn("Failed to remove coalesced job from processing", "jobId", jobs[i].ID, "projectId", projectID, "mrId", mergeRequestIID, "error", err)
+			}
+		}
+		if len(jobs) > 1 {
+			qm.log.Info("Coalesced queued jobs", "jobId", job.ID, "projectId", projectID, "mrId", mergeRequestIID, "jobs", len(jobs))
+			qm.incrementCounterBy(c, statCoalesced, int64(len(jobs)-1))
+		}
+		qm.processJob(lockCtx, lock, job, jobData, processor)
 
-		// Mark job as processing
-		if err := qm.markJobAsProcessing(c, job); err != nil {
-			qm.log.Warn("Failed to mark job as processing", "jobId", job.ID, "projectId", projectID, "mrId", mergeRequestIID, "error", err)
+		// Leave the remaining jobs queued while draining or to the new lock holder
+		if qm.stopping() || !lock.held() {
+			return true, nil
+		}
+		if settling, err := qm.isSettling(c, projectID, mergeRequestIID); err != nil || settling {
+			return settling, err
 		}
+	}
+}
 
-		// Execute the job
-		if err := processor.ProcessJob(c, job); err != nil {
-			qm.log.Error("Error processing job", "jobId", job.ID, "projectId", projectID, "mrId", mergeRequestIID, "error", err)
-			if err := qm.handleJobFailure(c, job, queueKey, err); err != nil {
-				qm.log.Error("Error handling job", "jobId", job.ID, "projectId", projectID, "mrId", mergeRequestIID, "error", err)
-			}
-		} else {
-			qm.log.Info("Successfully processed job", "jobId", job.ID, "projectId", projectID, "mrId", mergeRequestIID)
-			// Remove from processing set on success
-			if err := qm.removeJobFromProcessing(c, job); err != nil {
-				qm.log.Warn("Failed to remove job from processing", "jobId", job.ID, "projectId", projectID, "mrId", mergeRequestIID, "error", err)
-			}
+// latestJob returns the job with the newest payload. Retried jobs are
+// requeued at the head of the queue, so queue order alone is not enough.
+func latestJob(jobs []*WebhookJob) *WebhookJob {
+	var latest *WebhookJob
+	// Jobs are ordered newest first; on equal timestamps the newer one wins
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if latest == nil || jobs[i].CreatedAt >= latest.CreatedAt {
+			latest = jobs[i]
 		}
 	}
+	return latest
+}
 
-	return nil
+// isSettling reports whether an event arrived for the MR within the debounce window
+func (qm *QueueManager) isSettling(c context.Context, projectID, mergeRequestIID string) (bool, error) {
+	if qm.debounce <= 0 {
+		return false, nil
+	}
+	n, err := qm.redis.Exists(c, qm.getDebounceKey(projectID, mergeRequestIID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to read debounce window: %w", err)
+	}
+	return n > 0, nil
 }
 
-// StartProcessor starts the queue processor that continuously processes jobs
+// processJob runs a dequeued job, marked in processing with marked, in a span
+// linked to the webhook request that queued it. A job that fails after its
+// worker lost the MR lock is left in processing, to be requeued by the reaper
+// once its visibility timeout passed.
+func (qm *QueueManager) processJob(c context.Context, lock *mrLock, job *WebhookJob, marked string, processor JobProcessor) {
+	c, span := startJobSpan(c, job)
+	defer span.End()
+
+	// An attempt that started without finishing was interrupted, possibly by
+	// this very job crashing its worker
+	if len(job.AttemptedAt) > job.Attempts {
+		job.Attempts = len(job.AttemptedAt) - 1
+		qm.log.Warn("Previous attempt of job was interrupted", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "attempt", job.Attempts+1)
+		if err := qm.handleJobFailure(c, job, marked, errInterrupted, processor); err != nil {
+			qm.log.Error("Error handling job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+		}
+		return
+	}
+
+	qm.log.Info("Processing job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID)
+	job.AttemptedAt = append(job.AttemptedAt, time.Now().Unix())
+
+	// Record the attempt in processing, so an interrupted attempt counts
+	// towards the maximum attempts. A job that cannot be marked is not run:
+	// it is requeued as it was dequeued, or else left to the reaper.
+	dequeued := marked
+	marked, err := qm.markJobAsProcessing(c, job, dequeued)
+	if err != nil {
+		qm.log.Warn("Failed to mark job as processing, requeueing it", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+		if err := qm.requeueJob(c, job, dequeued); err != nil {
+			qm.log.Error("Failed to requeue job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+		}
+		return
+	}
+	lock.setJob(marked)
+	defer lock.setJob("")
+
+	// Execute the job
+	err = processor.ProcessJob(c, job)
+	if err != nil {
+		tracing.RecordError(span, err)
+		if !lock.held() {
+			qm.log.Warn("Lost lock while processing job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+			return
+		}
+		qm.log.Error("Error processing job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+		if err := qm.handleJobFailure(c, job, marked, err, processor); err != nil {
+			qm.log.Error("Error handling job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+		}
+		return
+	}
+
+	qm.log.Info("Successfully processed job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID)
+	// Remove from processing on success, also after losing the lock: the
+	// reaper would otherwise retry the job as interrupted
+	if err := qm.removeJobFromProcessing(context.WithoutCancel(c), job, marked); err != nil {
+		qm.log.Warn("Failed to remove job from processing", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+	}
+}
+
+// StartProcessor starts the queue processor: a dispatcher hands indexed MR
+// queues to a pool of workers, which process different MRs in parallel. The
+// per-MR lock keeps the jobs of one MR in order, also across replicas.
+// Cancelling c stops dispatching; jobs in flight are only cancelled by
+// StopProcessor once its drain deadline passes.
 func (qm *QueueManager) StartProcessor(c context.Context, processor JobProcessor) {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
 	if qm.isProcessing {
 		qm.log.Info("Queue processor is already running")
 		return
 	}
 
 	qm.isProcessing = true
-	qm.log.Info("Starting GitLab MR queue processor")
+	qm.log.Info("Starting GitLab MR queue processor", "workers", qm.workers)
+
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(c))
+	qm.cancelJobs = cancelJobs
 
+	d := &dispatcher{
+		work:     make(chan string),
+		inFlight: make(map[string]bool),
+	}
+
+	for i := 0; i < qm.workers; i++ {
+		qm.wg.Add(1)
+		go func() {
+			defer qm.wg.Done()
+			qm.runWorker(jobCtx, d, processor)
+		}()
+	}
+
+	qm.wg.Add(1)
 	go func() {
 		defer func() {
-			qm.isProcessing = false
-			qm.log.Info("Queue processor stopped")
+			close(d.work)
+			qm.wg.Done()
 		}()
+		qm.runDispatcher(c, d)
+	}()
+}
 
-		ticker := time.NewTicker(qm.processingInterval)
-		defer ticker.Stop()
+// dispatcher tracks the MRs handed to the workers of this replica
+type dispatcher struct {
+	work chan string
 
-		for {
+	mu       sync.Mutex
+	inFlight map[string]bool
+	// pending is set when a worker left jobs behind for a later run
+	pending bool
+}
+
+// runDispatcher hands every indexed MR that is not already being processed
+// here to a worker, until c is cancelled or the processor is stopped
+func (qm *QueueManager) runDispatcher(c context.Context, d *dispatcher) {
+	for {
+		if c.Err() != nil || qm.stopping() {
+			return
+		}
+
+		pending, err := qm.dispatchQueues(c, d)
+		if err != nil {
+			qm.log.Error("Error processing queues", "error", err)
+		}
+
+		// Poll while jobs wait for a debounce window, a lock or a free worker,
+		// otherwise block until a job is queued
+		if pending || err != nil || !qm.waitForWork(c) {
 			select {
 			case <-c.Done():
 				return
 			case <-qm.stopChan:
 				return
-			case <-ticker.C:
-				if err := qm.processAllQueues(c, processor); err != nil {
-					qm.log.Error("Error processing queues", "error", err)
-				}
+			case <-time.After(qm.processingInterval):
 			}
 		}
-	}()
+	}
 }
 
-// StopProcessor stops the queue processor
-func (qm *QueueManager) StopProcessor() {
-	if !qm.isProcessing {
-		return
+// dispatchQueues hands the indexed MRs to the workers and reports whether
+// jobs are left pending
+func (qm *QueueManager) dispatchQueues(c context.Context, d *dispatcher) (bool, error) {
+	if err := qm.promoteRetries(c); err != nil {
+		qm.log.Error("Failed to queue due retries", "error", err)
+	}
+	if err := qm.reapStuckJobs(c); err != nil {
+		qm.log.Error("Failed to requeue stuck jobs", "error", err)
+	}
+
+	members, err := qm.redis.SMembers(c, qm.getIndexKey()).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to get queue index: %w", err)
+	}
+
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = false
+	d.mu.Unlock()
+
+	for _, member := range members {
+		d.mu.Lock()
+		busy := d.inFlight[member]
+		if !busy {
+			d.inFlight[member] = true
+		}
+		d.mu.Unlock()
+		if busy {
+			pending = true
+			continue
+		}
+
+		select {
+		case d.work <- member:
+		case <-c.Done():
+			return pending, nil
+		case <-qm.stopChan:
+			return pending, nil
+		}
+	}
+
+	return pending, nil
+}
+
+// runWorker processes the MRs handed out by the dispatcher until it stops
+func (qm *QueueManager) runWorker(c context.Context, d *dispatcher, processor JobProcessor) {
+	for member := range d.work {
+		pending := true
+		if projectID, mergeRequestIID, ok := strings.Cut(member, ":"); ok {
+			var err error
+			if pending, err = qm.processMRQueue(c, projectID, mergeRequestIID, processor); err != nil {
+				qm.log.Info("Error processing MR queue", "projectId", projectID, "mrId", mergeRequestIID, "error", err)
+			}
+		} else {
+			qm.log.Warn("Invalid queue index entry", "member", member)
+		}
+
+		d.mu.Lock()
+		delete(d.inFlight, member)
+		d.pending = d.pending || pending
+		d.mu.Unlock()
+	}
+}
+
+// waitForWork blocks until a job is queued or wakeupTimeout passes. It
+// returns false when waiting failed, so the caller falls back to polling.
+func (qm *QueueManager) waitForWork(c context.Context) bool {
+	err := qm.redis.BRPop(c, wakeupTimeout, qm.getWakeupKey()).Err()
+	if err != nil && err != redis.Nil {
+		if c.Err() == nil {
+			qm.log.Warn("Failed to wait for queued jobs", "error", err)
+		}
+		return false
 	}
+	return true
+}
 
+// StopProcessor stops dispatching and waits for the workers to finish their
+// current job; queued jobs are left for the next start or another replica.
+// When c is done first, jobs in flight are cancelled and c's error returned.
+func (qm *QueueManager) StopProcessor(c context.Context) error {
+	qm.mu.Lock()
+	if !qm.isProcessing {
+		qm.mu.Unlock()
+		return nil
+	}
+	qm.isProcessing = false
 	qm.log.Info("Stopping GitLab MR queue processor")
 	close(qm.stopChan)
+	qm.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		qm.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-c.Done():
+		err = c.Err()
+		qm.log.Warn("Cancelling jobs in flight", "error", err)
+		qm.cancelJobs()
+		<-done
+	}
+	qm.cancelJobs()
+
+	qm.log.Info("Queue processor stopped")
+	return err
+}
+
+// stopping reports whether StopProcessor was called
+func (qm *QueueManager) stopping() bool {
+	select {
+	case <-qm.stopChan:
+		return true
+	default:
+		return false
+	}
 }
 
 // GetQueueStats returns statistics about the queues
 func (qm *QueueManager) GetQueueStats(c context.Context) (*QueueStats, error) {
-	queueKeys, err := qm.redis.Keys(c, qm.queuePrefix+":*").Result()
+	members, err := qm.redis.SMembers(c, qm.getIndexKey()).Result()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get queue keys: %w", err)
+		return nil, fmt.Errorf("failed to get queue index: %w", err)
 	}
 
-	processingKeys, err := qm.redis.Keys(c, qm.processingPrefix+":*").Result()
+	processingJobs, err := qm.countProcessingJobs(c)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get processing keys: %w", err)
+		return nil, fmt.Errorf("failed to get processing jobs: %w", err)
+	}
+
+	lengths := make([]*redis.IntCmd, len(members))
+	if _, err := qm.redis.Pipelined(c, func(pipe redis.Pipeliner) error {
+		for i, member := range members {
+			projectID, mergeRequestIID, _ := strings.Cut(member, ":")
+			lengths[i] = pipe.LLen(c, qm.getQueueKey(projectID, mergeRequestIID))
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get queue lengths: %w", err)
 	}
 
 	var totalJobs int64
 	var queueDetails []QueueDetail
 
-	for _, queueKey := range queueKeys {
-		parts := strings.Split(queueKey, ":")
-		if len(parts) >= 5 {
-			projectID := parts[3]
-			mergeRequestIID := parts[4]
+	for i, member := range members {
+		jobCount := lengths[i].Val()
+		totalJobs += jobCount
+
+		if jobCount > 0 {
+			projectID, mergeRequestIID, _ := strings.Cut(member, ":")
+			queueDetails = append(queueDetails, QueueDetail{
+				ProjectID:       projectID,
+				MergeRequestIID: mergeRequestIID,
+				JobCount:        int(jobCount),
+			})
+		}
+	}
 
-			jobCount, err := qm.redis.LLen(c, queueKey).Result()
-			if err != nil {
-				qm.log.Warn("Failed to get queue length", "key", queueKey, "error", err)
-				continue
-			}
+	retries, err := qm.getCounter(c, statRetries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retry count: %w", err)
+	}
+	deadJobs, err := qm.getCounter(c, statDeadJobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead job count: %w", err)
+	}
 
-			totalJobs += jobCount
+	delayedJobs, err := qm.redis.ZCard(c, qm.getRetryKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delayed retries: %w", err)
+	}
 
-			if jobCount > 0 {
-				queueDetails = append(queueDetails, QueueDetail{
-					ProjectID:       projectID,
-					MergeRequestIID: mergeRequestIID,
-					JobCount:        int(jobCount),
-				})
-			}
-		}
+	coalesced, err := qm.getCounter(c, statCoalesced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coalesced job count: %w", err)
+	}
+	reaped, err := qm.getCounter(c, statReaped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reaped job count: %w", err)
 	}
 
 	return &QueueStats{
-		TotalQueues:    len(queueKeys),
+		TotalQueues:    len(queueDetails),
 		TotalJobs:      int(totalJobs),
-		ProcessingJobs: len(processingKeys),
+		ProcessingJobs: int(processingJobs),
+		Retries:        retries,
+		DeadJobs:       deadJobs,
+		Coalesced:      coalesced,
+		Reaped:         reaped,
+		DelayedJobs:    int(delayedJobs),
 		QueueDetails:   queueDetails,
 	}, nil
 }
 
 // QueueStats represents queue statistics
 type QueueStats struct {
-	TotalQueues    int           `json:"total_queues"`
-	TotalJobs      int           `json:"total_jobs"`
-	ProcessingJobs int           `json:"processing_jobs"`
-	QueueDetails   []QueueDetail `json:"queue_details"`
+	TotalQueues    int `json:"total_queues"`
+	TotalJobs      int `json:"total_jobs"`
+	ProcessingJobs int `json:"processing_jobs"`
+	// DelayedJobs are failed jobs waiting for their retry
+	DelayedJobs int `json:"delayed_jobs"`
+	// Retries and DeadJobs count requeued jobs and jobs dropped after their
+	// last attempt since the counters were created
+	Retries  int64 `json:"retries"`
+	DeadJobs int64 `json:"dead_jobs"`
+	// Coalesced counts jobs superseded by a newer job of the same MR
+	Coalesced int64 `json:"coalesced"`
+	// Reaped counts jobs requeued after their worker crashed or lost its lock
+	Reaped       int64         `json:"reaped"`
+	QueueDetails []QueueDetail `json:"queue_details"`
 }
 
 // QueueDetail represents details about a specific queue
@@ -307,28 +743,42 @@ func (qm *QueueManager) ClearAllQueues(c context.Context) error {
 		qm.queuePrefix + ":*",
 		qm.lockPrefix + ":*",
 		qm.processingPrefix + ":*",
+		qm.statsPrefix + ":*",
+		qm.eventPrefix + ":*",
+		qm.debouncePrefix + ":*",
+		qm.deadPrefix + ":*",
 	}
 
-	for _, pattern := range patterns {
-		keys, err := qm.redis.Keys(c, pattern).Result()
-		if err != nil {
-			return fmt.Errorf("failed to get keys for pattern %s: %w", pattern, err)
-		}
+	// SCAN only covers the node it is sent to
+	if cluster, ok := qm.redis.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(c, func(c context.Context, node *redis.Client) error {
+			return deleteKeys(c, node, patterns)
+		})
+	}
+	return deleteKeys(c, qm.redis, patterns)
+}
 
-		if len(keys) > 0 {
-			if err := qm.redis.Del(c, keys...).Err(); err != nil {
+func deleteKeys(c context.Context, rdb redis.UniversalClient, patterns []string) error {
+	for _, pattern := range patterns {
+		iter := rdb.Scan(c, 0, pattern, 100).Iterator()
+		for iter.Next(c) {
+			if err := rdb.Del(c, iter.Val()).Err(); err != nil {
 				return fmt.Errorf("failed to delete keys: %w", err)
 			}
 		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("failed to scan keys for pattern %s: %w", pattern, err)
+		}
 	}
-
 	return nil
 }
 
-// Close gracefully shuts down the queue manager
+// Close gracefully shuts down the queue manager, after jobs in flight
+// finished or were cancelled at the drain timeout
 func (qm *QueueManager) Close() error {
-	qm.StopProcessor()
-	return qm.redis.Close()
+	c, cancel := context.WithTimeout(context.Background(), qm.drainTimeout)
+	defer cancel()
+	return errors.Join(qm.StopProcessor(c), qm.redis.Close())
 }
 
 // Health checks if the queue manager is healthy
@@ -336,108 +786,288 @@ func (qm *QueueManager) Health(c context.Context) error {
 	return qm.redis.Ping(c).Err()
 }
 
+// MarkDelivery records a webhook delivery for ttl and reports whether it was not seen before
+func (qm *QueueManager) MarkDelivery(c context.Context, deliveryID string, ttl time.Duration) (bool, error) {
+	return qm.redis.SetNX(c, qm.getEventKey(deliveryID), time.Now().Unix(), ttl).Result()
+}
+
+// ForgetDelivery removes a recorded delivery, so a retry of it is processed again
+func (qm *QueueManager) ForgetDelivery(c context.Context, deliveryID string) error {
+	return qm.redis.Del(c, qm.getEventKey(deliveryID)).Err()
+}
+
 // Private helper methods
 
+// Keys are hash tagged for Redis Cluster: the keys of an MR share the slot
+// of its tag, so MRs are spread across the cluster, while keys updated
+// together by every MR share sharedTag.
+
+// sharedTag is the hash tag of the index, wakeup, retry, processing index
+// and dead-letter keys
+const sharedTag = "{mr-conform}"
+
+// mrTag is the hash tag of the queue, processing, lock and debounce keys of an MR
+func mrTag(projectID, mergeRequestIID string) string {
+	return "{" + queueMember(projectID, mergeRequestIID) + "}"
+}
+
+// sharedKey returns a key in the slot of sharedTag
+func sharedKey(prefix, name string) string {
+	return prefix + ":" + sharedTag + ":" + name
+}
+
 func (qm *QueueManager) getQueueKey(projectID, mergeRequestIID string) string {
-	return fmt.Sprintf("%s:%s:%s", qm.queuePrefix, projectID, mergeRequestIID)
+	return qm.queuePrefix + ":" + mrTag(projectID, mergeRequestIID)
+}
+
+// getIndexKey is the set of MRs with a queue, as "projectID:mergeRequestIID"
+func (qm *QueueManager) getIndexKey() string {
+	return sharedKey(qm.queuePrefix, "index")
+}
+
+// getWakeupKey is the list processors block on, pushed to on every enqueue
+func (qm *QueueManager) getWakeupKey() string {
+	return sharedKey(qm.queuePrefix, "wakeup")
+}
+
+// getProcessingIndexKey is the set of MRs with jobs in processing, as
+// "projectID:mergeRequestIID"
+func (qm *QueueManager) getProcessingIndexKey() string {
+	return sharedKey(qm.processingPrefix, "index")
+}
+
+// getProcessingKey is the sorted set of the processing jobs of an MR, by
+// their data, scored by the end of their visibility timeout in milliseconds.
+// It shares the slot of the queue, so jobs move between them atomically.
+func (qm *QueueManager) getProcessingKey(projectID, mergeRequestIID string) string {
+	return qm.processingPrefix + ":" + mrTag(projectID, mergeRequestIID)
+}
+
+func queueMember(projectID, mergeRequestIID string) string {
+	return projectID + ":" + mergeRequestIID
 }
 
 func (qm *QueueManager) getLockKey(projectID, mergeRequestIID string) string {
-	return fmt.Sprintf("%s:%s:%s", qm.lockPrefix, projectID, mergeRequestIID)
+	return qm.lockPrefix + ":" + mrTag(projectID, mergeRequestIID)
 }
 
-func (qm *QueueManager) getProcessingKey(jobID string) string {
-	return fmt.Sprintf("%s:%s", qm.processingPrefix, jobID)
+// getFenceKey is the counter that fencing tokens of MR locks are taken from
+func (qm *QueueManager) getFenceKey() string {
+	return qm.lockPrefix + ":fence"
 }
 
-func (qm *QueueManager) acquireLock(c context.Context, lockKey string) (bool, error) {
-	result, err := qm.redis.SetNX(c, lockKey, time.Now().Unix(), qm.defaultLockTTL).Result()
-	if err != nil {
-		return false, err
+func (qm *QueueManager) getDebounceKey(projectID, mergeRequestIID string) string {
+	return qm.debouncePrefix + ":" + mrTag(projectID, mergeRequestIID)
+}
+
+func (qm *QueueManager) getEventKey(deliveryID string) string {
+	return fmt.Sprintf("%s:%s", qm.eventPrefix, deliveryID)
+}
+
+// queueTTL expires the queue of an MR that is not processed
+const queueTTL = 24 * time.Hour
+
+// wakeupTimeout bounds how long an idle processor blocks before looking at
+// the queue index again; Redis blocks for whole seconds only
+const wakeupTimeout = time.Second
+
+// maxPendingWakeups caps the wakeup list while no processor is waiting
+const maxPendingWakeups = 64
+
+// Counters kept under the stats prefix
+const (
+	statRetries   = "retries"
+	statDeadJobs  = "dead_jobs"
+	statCoalesced = "coalesced"
+	statReaped    = "reaped"
+)
+
+func (qm *QueueManager) incrementCounter(c context.Context, name string) {
+	qm.incrementCounterBy(c, name, 1)
+}
+
+func (qm *QueueManager) incrementCounterBy(c context.Context, name string, n int64) {
+	if err := qm.redis.IncrBy(c, fmt.Sprintf("%s:%s", qm.statsPrefix, name), n).Err(); err != nil {
+		qm.log.Warn("Failed to update queue statistics", "counter", name, "error", err)
 	}
-	return result, nil
 }
 
-func (qm *QueueManager) releaseLock(c context.Context, lockKey string) error {
-	return qm.redis.Del(c, lockKey).Err()
+func (qm *QueueManager) getCounter(c context.Context, name string) (int64, error) {
+	value, err := qm.redis.Get(c, fmt.Sprintf("%s:%s", qm.statsPrefix, name)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return value, err
 }
 
-func (qm *QueueManager) dequeueJob(c context.Context, queueKey string) (*WebhookJob, error) {
-	jobData, err := qm.redis.RPop(c, queueKey).Result()
+// dequeueScript moves all jobs of a queue into the processing set of its MR,
+// marked until the end of their visibility timeout
+var dequeueScript = redis.NewScript(`
+local jobs = redis.call("LRANGE", KEYS[1], 0, -1)
+if #jobs > 0 then
+	redis.call("DEL", KEYS[1])
+	for _, data in ipairs(jobs) do
+		redis.call("ZADD", KEYS[2], ARGV[1], data)
+	end
+end
+return jobs
+`)
+
+// dequeueJobs atomically takes all jobs of the queue of an MR, newest first,
+// and marks them as processing, so the reaper requeues them if their worker
+// crashes. It returns the jobs with the data each one is marked with.
+func (qm *QueueManager) dequeueJobs(c context.Context, projectID, mergeRequestIID string) (jobs []*WebhookJob, marked []string, err error) {
+	queueKey := qm.getQueueKey(projectID, mergeRequestIID)
+	c, span := tracing.Start(c, "queue.dequeue", trace.WithAttributes(attribute.String("queue.key", queueKey)))
+	defer func() {
+		span.SetAttributes(attribute.Int("queue.jobs", len(jobs)))
+		tracing.End(span, err)
+	}()
+
+	// Index the MR before marking its jobs, so the reaper finds every marked job
+	processingKey := qm.getProcessingKey(projectID, mergeRequestIID)
+	if err := qm.redis.SAdd(c, qm.getProcessingIndexKey(), queueMember(projectID, mergeRequestIID)).Err(); err != nil {
+		return nil, nil, err
+	}
+	deadline := time.Now().Add(qm.visibilityTimeout).UnixMilli()
+	values, err := dequeueScript.Run(c, qm.redis, []string{queueKey, processingKey}, deadline).StringSlice()
 	if err != nil {
-		if err == redis.Nil {
-			return nil, nil // No jobs in queue
-		}
-		return nil, err
+		return nil, nil, err
 	}
 
-	var job WebhookJob
-	if err := json.Unmarshal([]byte(jobData), &job); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal job data: %w", err)
+	for _, jobData := range values {
+		job := &WebhookJob{}
+		if err := json.Unmarshal([]byte(jobData), job); err != nil {
+			qm.log.Error("Dropping undecodable job", "key", queueKey, "error", err)
+			if err := qm.redis.ZRem(c, processingKey, jobData).Err(); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		jobs = append(jobs, job)
+		marked = append(marked, jobData)
 	}
 
-	return &job, nil
+	return jobs, marked, nil
 }
 
-func (qm *QueueManager) markJobAsProcessing(c context.Context, job *WebhookJob) error {
-	processingKey := qm.getProcessingKey(job.ID)
-	jobData, err := json.Marshal(job)
-	if err != nil {
+// unindexQueue removes an MR with an empty queue from the queue index. The
+// queue is checked after unindexing it and indexed again if a job was queued
+// in the meantime: queueJobData queues before indexing, so either this check
+// sees the job or its indexing follows the removal.
+func (qm *QueueManager) unindexQueue(c context.Context, projectID, mergeRequestIID string) error {
+	member := queueMember(projectID, mergeRequestIID)
+	if err := qm.redis.SRem(c, qm.getIndexKey(), member).Err(); err != nil {
 		return err
 	}
-	return qm.redis.Set(c, processingKey, jobData, qm.defaultLockTTL).Err()
+	n, err := qm.redis.LLen(c, qm.getQueueKey(projectID, mergeRequestIID)).Result()
+	if err != nil || n == 0 {
+		return err
+	}
+	return qm.redis.SAdd(c, qm.getIndexKey(), member).Err()
 }
 
-func (qm *QueueManager) removeJobFromProcessing(c context.Context, job *WebhookJob) error {
-	processingKey := qm.getProcessingKey(job.ID)
-	return qm.redis.Del(c, processingKey).Err()
+// unindexProcessing removes an MR without processing jobs from the
+// processing index, checking again after unindexing it like unindexQueue
+func (qm *QueueManager) unindexProcessing(c context.Context, projectID, mergeRequestIID string) error {
+	member := queueMember(projectID, mergeRequestIID)
+	n, err := qm.redis.ZCard(c, qm.getProcessingKey(projectID, mergeRequestIID)).Result()
+	if err != nil || n > 0 {
+		return err
+	}
+	if err := qm.redis.SRem(c, qm.getProcessingIndexKey(), member).Err(); err != nil {
+		return err
+	}
+	if n, err = qm.redis.ZCard(c, qm.getProcessingKey(projectID, mergeRequestIID)).Result(); err != nil || n == 0 {
+		return err
+	}
+	return qm.redis.SAdd(c, qm.getProcessingIndexKey(), member).Err()
 }
 
-func (qm *QueueManager) handleJobFailure(c context.Context, job *WebhookJob, queueKey string, jobErr error) error {
-	job.Attempts++
-
-	if job.Attempts < job.MaxAttempts {
-		// Requeue the job for retry
-		qm.log.Info("Retrying job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "attempt", job.Attempts, "maxAttempts", job.MaxAttempts)
-		jobData, err := json.Marshal(job)
-		if err != nil {
-			return fmt.Errorf("failed to marshal job for retry: %w", err)
-		}
-		return qm.redis.LPush(c, queueKey, jobData).Err()
+// markJobAsProcessing replaces the processing entry of a job, marked with
+// previous when it was dequeued, with its current data and a new visibility
+// timeout. It returns the data the job is marked with.
+func (qm *QueueManager) markJobAsProcessing(c context.Context, job *WebhookJob, previous string) (string, error) {
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return "", err
 	}
+	processingKey := qm.getProcessingKey(job.ProjectID, job.MergeRequestIID)
+	_, err = qm.redis.TxPipelined(c, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.ZRem(c, processingKey, previous)
+		}
+		pipe.ZAdd(c, processingKey, &redis.Z{
+			Score:  float64(time.Now().Add(qm.visibilityTimeout).UnixMilli()),
+			Member: jobData,
+		})
+		return nil
+	})
+	return string(jobData), err
+}
 
-	// Job has exceeded max attempts, log and remove from processing
-	//log.Printf("Job %s failed after %d attempts: %v", job.ID, job.MaxAttempts, jobErr)
-	qm.log.Info("Job failed after max attempts", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "maxAttempts", job.MaxAttempts, "error", jobErr)
-	return qm.removeJobFromProcessing(c, job)
+// removeJobFromProcessing removes a job marked with jobData from processing.
+// A job the reaper requeued in the meantime is no longer marked with it, so
+// its new attempt is not removed.
+func (qm *QueueManager) removeJobFromProcessing(c context.Context, job *WebhookJob, jobData string) error {
+	if jobData == "" {
+		return nil
+	}
+	return qm.redis.ZRem(c, qm.getProcessingKey(job.ProjectID, job.MergeRequestIID), jobData).Err()
 }
 
-func (qm *QueueManager) processAllQueues(c context.Context, processor JobProcessor) error {
-	queueKeys, err := qm.redis.Keys(c, qm.queuePrefix+":*").Result()
+// countProcessingJobs counts the jobs being processed, stuck jobs included until they are reaped
+func (qm *QueueManager) countProcessingJobs(c context.Context) (int64, error) {
+	members, err := qm.redis.SMembers(c, qm.getProcessingIndexKey()).Result()
 	if err != nil {
-		return fmt.Errorf("failed to get queue keys: %w", err)
-	}
-
-	for _, queueKey := range queueKeys {
-		parts := strings.Split(queueKey, ":")
-		if len(parts) >= 5 {
-			projectID := parts[3]
-			mergeRequestIID := parts[4]
-			// Check if there are jobs in this queue
-			queueLength, err := qm.redis.LLen(c, queueKey).Result()
-			if err != nil {
-				//log.Printf("Warning: failed to get queue length for %s: %v", queueKey, err)
-				qm.log.Warn("Failed to get queue length", "key", queueKey, "error", err)
-				continue
-			}
+		return 0, err
+	}
+	counts := make([]*redis.IntCmd, len(members))
+	if _, err := qm.redis.Pipelined(c, func(pipe redis.Pipeliner) error {
+		for i, member := range members {
+			projectID, mergeRequestIID, _ := strings.Cut(member, ":")
+			counts[i] = pipe.ZCard(c, qm.getProcessingKey(projectID, mergeRequestIID))
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, count := range counts {
+		total += count.Val()
+	}
+	return total, nil
+}
 
-			if queueLength > 0 {
-				if err := qm.ProcessMRQueue(c, projectID, mergeRequestIID, processor); err != nil {
-					qm.log.Info("Error processing MR queue", "projectId", projectID, "mrId", mergeRequestIID, "error", err)
-				}
-			}
+// handleJobFailure retries or dead-letters a failed job, then removes it from
+// processing if it was marked with marked
+func (qm *QueueManager) handleJobFailure(c context.Context, job *WebhookJob, marked string, jobErr error, processor JobProcessor) error {
+	job.Attempts++
+	job.LastError = jobErr.Error()
+
+	switch {
+	case IsPermanent(jobErr):
+		qm.log.Info("Job failed with a non-retryable error", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "attempt", job.Attempts, "error", jobErr)
+	case job.Attempts < job.MaxAttempts:
+		// Schedule the job for retry once its backoff passed
+		delay := retryDelay(qm.retryBackoffMin, qm.retryBackoffMax, job.Attempts)
+		qm.log.Info("Retrying job", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "attempt", job.Attempts, "maxAttempts", job.MaxAttempts, "delay", delay)
+		if err := qm.scheduleRetry(c, job, time.Now().Add(delay)); err != nil {
+			return err
 		}
+		qm.incrementCounter(c, statRetries)
+		return qm.removeJobFromProcessing(c, job, marked)
+	default:
+		qm.log.Info("Job failed after max attempts", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "maxAttempts", job.MaxAttempts, "error", jobErr)
 	}
 
-	return nil
+	// Move the job to the dead-letter queue
+	qm.incrementCounter(c, statDeadJobs)
+	if err := qm.addDeadJob(c, job); err != nil {
+		qm.log.Error("Failed to add job to the dead-letter queue", "jobId", job.ID, "projectId", job.ProjectID, "mrId", job.MergeRequestIID, "error", err)
+	}
+	if handler, ok := processor.(DeadJobHandler); ok {
+		handler.HandleDeadJob(c, job)
+	}
+	return qm.removeJobFromProcessing(c, job, marked)
 }
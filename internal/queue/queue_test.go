@@ -0,0 +1,361 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/redisclient"
+	"gitlab-mr-conformity-bot/pkg/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestQueueManager returns a queue manager on an in-process Redis server
+func newTestQueueManager(t *testing.T, config *Config) (*QueueManager, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	if config == nil {
+		config = &Config{}
+	}
+	config.Redis = redisclient.Config{Addr: server.Addr()}
+
+	qm, err := NewQueueManager(config, logger.New())
+	if err != nil {
+		t.Fatalf("failed to create queue manager: %v", err)
+	}
+	t.Cleanup(func() { qm.Close() })
+	return qm, server
+}
+
+func TestLatestJob(t *testing.T) {
+	// Newest first, as stored by LPUSH; the retried job was requeued last
+	jobs := []*WebhookJob{
+		{ID: "retried", CreatedAt: 100},
+		{ID: "newest", CreatedAt: 120},
+		{ID: "same-second", CreatedAt: 110},
+		{ID: "oldest", CreatedAt: 110},
+	}
+	if got := latestJob(jobs); got.ID != "newest" {
+		t.Errorf("expected newest job, got %q", got.ID)
+	}
+
+	if got := latestJob(jobs[2:]); got.ID != "same-second" {
+		t.Errorf("expected the later queued job on equal timestamps, got %q", got.ID)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	min, max := time.Second, 10*time.Second
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: max} {
+		got := retryDelay(min, max, attempts)
+		if got > want || got < want*8/10 {
+			t.Errorf("attempt %d: delay %v, want %v minus up to 20%% jitter", attempts, got, want)
+		}
+	}
+}
+
+func TestPromoteRetries(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, nil)
+
+	due := &WebhookJob{ID: "due", ProjectID: "1", MergeRequestIID: "2", Attempts: 1}
+	later := &WebhookJob{ID: "later", ProjectID: "1", MergeRequestIID: "3", Attempts: 1}
+	if err := qm.scheduleRetry(c, due, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := qm.scheduleRetry(c, later, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := qm.promoteRetries(c); err != nil {
+		t.Fatalf("promote failed: %v", err)
+	}
+
+	jobs, _, err := qm.dequeueJobs(c, "1", "2")
+	if err != nil || len(jobs) != 1 || jobs[0].ID != "due" {
+		t.Fatalf("expected the due retry to be queued, got %v (%v)", jobs, err)
+	}
+	if member, _ := qm.redis.SIsMember(c, qm.getIndexKey(), "1:2").Result(); !member {
+		t.Error("expected the MR of the due retry to be indexed")
+	}
+	retries, _ := qm.redis.ZRange(c, qm.getRetryKey(), 0, -1).Result()
+	if len(retries) != 1 {
+		t.Fatalf("expected only the later retry to wait, got %d", len(retries))
+	}
+	var waiting WebhookJob
+	if err := json.Unmarshal([]byte(retries[0]), &waiting); err != nil || waiting.ID != "later" {
+		t.Errorf("expected the later retry to wait, got %q", retries[0])
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	err := fmt.Errorf("check failed: %w", Permanent(errors.New("merge request not found")))
+	if !IsPermanent(err) {
+		t.Error("expected wrapped permanent error to be permanent")
+	}
+	if IsPermanent(errors.New("connection reset")) {
+		t.Error("expected plain error to be retryable")
+	}
+	if Permanent(nil) != nil {
+		t.Error("expected Permanent(nil) to be nil")
+	}
+}
+
+func TestKeys_HashTags(t *testing.T) {
+	qm, err := NewQueueManager(&Config{Redis: redisclient.Config{Addr: "localhost:7000", Cluster: true}}, logger.New())
+	if err != nil {
+		t.Fatalf("failed to create queue manager: %v", err)
+	}
+	defer qm.Close()
+
+	// Keys used together by one script or transaction must share a slot
+	slots := map[string][]string{
+		"{1:2}": {qm.getQueueKey("1", "2"), qm.getProcessingKey("1", "2"), qm.getLockKey("1", "2"), qm.getDebounceKey("1", "2")},
+		sharedTag: {
+			qm.getIndexKey(), qm.getWakeupKey(), qm.getRetryKey(),
+			qm.getProcessingIndexKey(), qm.getDeadJobsKey(), qm.getDeadIndexKey(),
+		},
+	}
+	for tag, keys := range slots {
+		for _, key := range keys {
+			if got := hashTag(key); got != tag {
+				t.Errorf("expected key %q to have hash tag %q, got %q", key, tag, got)
+			}
+		}
+	}
+
+	// MRs are spread across slots
+	if hashTag(qm.getQueueKey("1", "3")) == hashTag(qm.getQueueKey("1", "2")) {
+		t.Error("expected queues of different MRs to have different hash tags")
+	}
+}
+
+// hashTag returns the part of a key Redis Cluster hashes, with its braces
+func hashTag(key string) string {
+	start := strings.Index(key, "{")
+	if start < 0 {
+		return key
+	}
+	end := strings.Index(key[start:], "}")
+	if end <= 1 {
+		return key
+	}
+	return key[start : start+end+1]
+}
+
+func TestEnqueueWebhook_IndexesAndWakesUp(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, nil)
+
+	jobID, err := qm.EnqueueWebhook(c, "1", "2", "merge_request", nil)
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	if member, _ := qm.redis.SIsMember(c, qm.getIndexKey(), "1:2").Result(); !member {
+		t.Error("expected the MR to be indexed")
+	}
+	if n, _ := qm.redis.LLen(c, qm.getWakeupKey()).Result(); n != 1 {
+		t.Errorf("expected a wakeup to be pushed, got %d", n)
+	}
+	jobs, _, err := qm.dequeueJobs(c, "1", "2")
+	if err != nil || len(jobs) != 1 || jobs[0].ID != jobID {
+		t.Errorf("expected the job in the MR queue, got %v (%v)", jobs, err)
+	}
+}
+
+func TestUnindexQueue(t *testing.T) {
+	c := context.Background()
+	qm, _ := newTestQueueManager(t, nil)
+
+	if _, err := qm.EnqueueWebhook(c, "1", "2", "merge_request", nil); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if _, _, err := qm.dequeueJobs(c, "1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	// A job arrives between dequeueing and unindexing
+	if _, err := qm.EnqueueWebhook(c, "1", "2", "merge_request", nil); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if err := qm.unindexQueue(c, "1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if member, _ := qm.redis.SIsMember(c, qm.getIndexKey(), "1:2").Result(); !member {
+		t.Error("expected the MR with a queued job to stay indexed")
+	}
+
+	if _, _, err := qm.dequeueJobs(c, "1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := qm.unindexQueue(c, "1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if member, _ := qm.redis.SIsMember(c, qm.getIndexKey(), "1:2").Result(); member {
+		t.Error("expected the MR with an empty queue to be unindexed")
+	}
+}
+
+// commandRecorder records the names of the commands sent to Redis
+type commandRecorder struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (r *commandRecorder) BeforeProcess(c context.Context, cmd redis.Cmder) (context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, cmd.Name())
+	return c, nil
+}
+
+func (r *commandRecorder) AfterProcess(c context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (r *commandRecorder) BeforeProcessPipeline(c context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		r.BeforeProcess(c, cmd)
+	}
+	return c, nil
+}
+
+func (r *commandRecorder) AfterProcessPipeline(c context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestClearAllQueues(t *testing.T) {
+	c := context.Background()
+	qm, server := newTestQueueManager(t, &Config{Debounce: time.Minute})
+
+	if _, err := qm.EnqueueWebhook(c, "1", "2", "merge_request", nil); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	if _, err := qm.markJobAsProcessing(c, &WebhookJob{ID: "processing", ProjectID: "1", MergeRequestIID: "3"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := qm.addDeadJob(c, &WebhookJob{ID: "dead", ProjectID: "1", MergeRequestIID: "4"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := qm.MarkDelivery(c, "delivery", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	qm.incrementCounter(c, statRetries)
+	server.Set("unrelated", "kept")
+
+	recorder := &commandRecorder{}
+	qm.redis.AddHook(recorder)
+	if err := qm.ClearAllQueues(c); err != nil {
+		t.Fatalf("clear failed: %v", err)
+	}
+
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "unrelated" {
+		t.Errorf("expected only the unrelated key to be left, got %v", keys)
+	}
+	scanned := false
+	for _, command := range recorder.commands {
+		if command == "keys" {
+			t.Error("expected keys to be scanned instead of listed with KEYS")
+		}
+		scanned = scanned || command == "scan"
+	}
+	if !scanned {
+		t.Error("expected keys to be scanned")
+	}
+}
+
+// blockingProcessor blocks every job until it is released or cancelled
+type blockingProcessor struct {
+	started  chan struct{}
+	release  chan struct{}
+	finished chan error
+}
+
+func newBlockingProcessor() *blockingProcessor {
+	return &blockingProcessor{
+		started:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+		finished: make(chan error, 1),
+	}
+}
+
+func (p *blockingProcessor) ProcessJob(c context.Context, job *WebhookJob) error {
+	p.started <- struct{}{}
+	var err error
+	select {
+	case <-p.release:
+	case <-c.Done():
+		err = c.Err()
+	}
+	p.finished <- err
+	return err
+}
+
+func startBlockedJob(t *testing.T, qm *QueueManager) *blockingProcessor {
+	t.Helper()
+	if _, err := qm.EnqueueWebhook(context.Background(), "1", "2", "merge_request", nil); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	processor := newBlockingProcessor()
+	qm.StartProcessor(context.Background(), processor)
+	select {
+	case <-processor.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the job to start")
+	}
+	return processor
+}
+
+func TestStopProcessor_WaitsForJobsInFlight(t *testing.T) {
+	qm, _ := newTestQueueManager(t, &Config{ProcessingInterval: 10 * time.Millisecond})
+	processor := startBlockedJob(t, qm)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- qm.StopProcessor(context.Background()) }()
+
+	select {
+	case <-stopped:
+		t.Fatal("expected StopProcessor to wait for the job in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(processor.release)
+	if err := <-stopped; err != nil {
+		t.Errorf("expected a clean stop, got %v", err)
+	}
+	if err := <-processor.finished; err != nil {
+		t.Errorf("expected the job to finish, got %v", err)
+	}
+}
+
+func TestStopProcessor_CancelsJobsAtDrainDeadline(t *testing.T) {
+	qm, _ := newTestQueueManager(t, &Config{ProcessingInterval: 10 * time.Millisecond})
+	processor := startBlockedJob(t, qm)
+
+	c, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := qm.StopProcessor(c); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the drain deadline to pass, got %v", err)
+	}
+	if err := <-processor.finished; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the job to be cancelled, got %v", err)
+	}
+}
+
+func TestClose_CancelsJobsAfterDrainTimeout(t *testing.T) {
+	qm, _ := newTestQueueManager(t, &Config{ProcessingInterval: 10 * time.Millisecond, DrainTimeout: 50 * time.Millisecond})
+	processor := startBlockedJob(t, qm)
+
+	if err := qm.Close(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the drain timeout to pass, got %v", err)
+	}
+	if err := <-processor.finished; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the job to be cancelled, got %v", err)
+	}
+}
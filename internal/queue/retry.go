@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// maxRetriesPerSweep bounds the retries queued by one sweep of the dispatcher
+const maxRetriesPerSweep = 100
+
+// permanentError marks an error that a retry would fail with again
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as non-retryable: a job failing with it is moved to
+// the dead-letter queue without further attempts
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// retryDelay grows exponentially with the number of failed attempts, bounded
+// by max, with up to 20% jitter so jobs failing together are not retried in lockstep
+func retryDelay(min, max time.Duration, attempts int) time.Duration {
+	wait := time.Duration(float64(min) * math.Pow(2, float64(attempts-1)))
+	if wait <= 0 || wait > max {
+		wait = max
+	}
+	return wait - time.Duration(rand.Float64()*0.2*float64(wait))
+}
+
+// getRetryKey is the sorted set of jobs waiting for their retry, scored by due time in milliseconds
+func (qm *QueueManager) getRetryKey() string {
+	return sharedKey(qm.queuePrefix, "retry")
+}
+
+func (qm *QueueManager) scheduleRetry(c context.Context, job *WebhookJob, due time.Time) error {
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job for retry: %w", err)
+	}
+	if err := qm.redis.ZAdd(c, qm.getRetryKey(), &redis.Z{Score: float64(due.UnixMilli()), Member: jobData}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+	return nil
+}
+
+// leaseScript returns the members of a sorted set that are due and
+// postpones them to the end of a lease, so concurrent sweeps do not take
+// them too. A member still in the set after its lease is taken again.
+var leaseScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, member in ipairs(due) do
+	redis.call("ZADD", KEYS[1], "XX", ARGV[3], member)
+end
+return due
+`)
+
+// leaseDue leases up to limit members of the sorted set key that are due
+func (qm *QueueManager) leaseDue(c context.Context, key string, limit int) ([]string, error) {
+	now := time.Now()
+	return leaseScript.Run(c, qm.redis, []string{key},
+		strconv.FormatInt(now.UnixMilli(), 10),
+		limit,
+		strconv.FormatInt(now.Add(qm.visibilityTimeout).UnixMilli(), 10),
+	).StringSlice()
+}
+
+// promoteRetries queues the retries that are due. A retry leaves the retry
+// set only once it was queued, so it is never lost; a sweep interrupted in
+// between queues it twice, and the copies are coalesced.
+func (qm *QueueManager) promoteRetries(c context.Context) error {
+	due, err := qm.leaseDue(c, qm.getRetryKey(), maxRetriesPerSweep)
+	if err != nil {
+		return err
+	}
+
+	for _, data := range due {
+		job := &WebhookJob{}
+		if err := json.Unmarshal([]byte(data), job); err != nil {
+			qm.log.Error("Dropping undecodable retry", "error", err)
+		} else if err := qm.queueJobData(c, job, data, false); err != nil {
+			return fmt.Errorf("failed to queue retry: %w", err)
+		}
+		if err := qm.redis.ZRem(c, qm.getRetryKey(), data).Err(); err != nil {
+			return err
+		}
+	}
+	if len(due) > 0 {
+		qm.log.Debug("Queued due retries", "count", len(due))
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// Package redisclient connects to Redis as a single server, through Sentinel
+// or as a cluster, shared by the queue and the Redis storage
+package redisclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Config holds the connection settings of a Redis deployment
+type Config struct {
+	// Addr is the server, or a seed node of a cluster without ClusterAddrs
+	Addr     string
+	Username string
+	Password string
+	// DB is not supported by Redis Cluster
+	DB int
+
+	// MasterName connects to the master monitored by the Sentinels at SentinelAddrs
+	MasterName       string
+	SentinelAddrs    []string
+	SentinelUsername string
+	SentinelPassword string
+
+	// Cluster connects to a Redis Cluster seeded with ClusterAddrs, or Addr
+	Cluster      bool
+	ClusterAddrs []string
+
+	TLS TLSConfig
+
+	// Pool settings; zero values keep the defaults of the Redis client
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+	IdleTimeout  time.Duration
+	MaxConnAge   time.Duration
+}
+
+// TLSConfig holds the TLS settings of Redis connections
+type TLSConfig struct {
+	Enabled bool
+	// CAFile verifies the server with these CA certificates instead of the system ones
+	CAFile string
+	// CertFile and KeyFile hold a client certificate for mutual TLS
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	// InsecureSkipVerify disables verification of the server certificate
+	InsecureSkipVerify bool
+}
+
+// New creates a client for the configured deployment. It does not connect
+// until the first command.
+func New(config Config) (redis.UniversalClient, error) {
+	if config.MasterName != "" && config.Cluster {
+		return nil, errors.New("redis sentinel and cluster mode cannot be combined")
+	}
+
+	tlsConfig, err := config.TLS.build()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            []string{config.Addr},
+		DB:               config.DB,
+		Username:         config.Username,
+		Password:         config.Password,
+		SentinelUsername: config.SentinelUsername,
+		SentinelPassword: config.SentinelPassword,
+		MasterName:       config.MasterName,
+		TLSConfig:        tlsConfig,
+		PoolSize:         config.PoolSize,
+		MinIdleConns:     config.MinIdleConns,
+		DialTimeout:      config.DialTimeout,
+		ReadTimeout:      config.ReadTimeout,
+		WriteTimeout:     config.WriteTimeout,
+		PoolTimeout:      config.PoolTimeout,
+		IdleTimeout:      config.IdleTimeout,
+		MaxConnAge:       config.MaxConnAge,
+	}
+
+	switch {
+	case config.MasterName != "":
+		if len(config.SentinelAddrs) == 0 {
+			return nil, errors.New("redis sentinel requires at least one sentinel address")
+		}
+		opts.Addrs = config.SentinelAddrs
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case config.Cluster:
+		if config.DB != 0 {
+			return nil, errors.New("redis cluster does not support selecting a database")
+		}
+		if len(config.ClusterAddrs) > 0 {
+			opts.Addrs = config.ClusterAddrs
+		}
+		return redis.NewClusterClient(opts.Cluster()), nil
+	default:
+		return redis.NewClient(opts.Simple()), nil
+	}
+}
+
+func (t TLSConfig) build() (*tls.Config, error) {
+	if !t.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in redis CA file %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,62 @@
+package redisclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestNew_SelectsClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		cluster bool
+	}{
+		{name: "single server", config: Config{Addr: "localhost:6379"}},
+		{name: "sentinel", config: Config{MasterName: "mymaster", SentinelAddrs: []string{"localhost:26379"}}},
+		{name: "cluster", config: Config{Addr: "localhost:7000", Cluster: true}, cluster: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer client.Close()
+
+			if _, ok := client.(*redis.ClusterClient); ok != tt.cluster {
+				t.Errorf("expected cluster client %v, got %T", tt.cluster, client)
+			}
+			if _, ok := client.(*redis.Client); !ok && !tt.cluster {
+				t.Errorf("expected a server client, got %T", client)
+			}
+		})
+	}
+}
+
+func TestNew_RejectsInvalidConfig(t *testing.T) {
+	emptyCA := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(emptyCA, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]Config{
+		"sentinel and cluster":    {MasterName: "mymaster", SentinelAddrs: []string{"localhost:26379"}, Cluster: true},
+		"sentinel without addrs":  {MasterName: "mymaster"},
+		"cluster with database":   {Addr: "localhost:7000", Cluster: true, DB: 1},
+		"missing CA file":         {Addr: "localhost:6379", TLS: TLSConfig{Enabled: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		"CA file without certs":   {Addr: "localhost:6379", TLS: TLSConfig{Enabled: true, CAFile: emptyCA}},
+		"client cert without key": {Addr: "localhost:6379", TLS: TLSConfig{Enabled: true, CertFile: emptyCA}},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := New(config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gitlab-mr-conformity-bot/internal/queue"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deadJobNote is posted on a merge request whose check failed its last attempt
+const deadJobNote = `### ⚠️ MR Conformity Check could not be completed
+
+The conformity check of this merge request failed after %d attempt(s), so the compliance report above may be out of date. Push a new commit or update the merge request to run it again, or ask an administrator to replay the check.`
+
+// requireAdmin protects the admin, history and report endpoints with the admin token,
+// sent as a bearer token. Without a configured token these endpoints are disabled.
+func (s *Server) requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := s.config.Server.AdminToken
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Endpoint is disabled, set server.admin_token to enable it"})
+			return
+		}
+
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireQueue rejects requests for queue resources while the queue is disabled
+func (s *Server) requireQueue(c *gin.Context) {
+	if !s.config.Queue.Enabled {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Queue is disabled"})
+		return
+	}
+	c.Next()
+}
+
+// handleListDeadJobs returns dead jobs, most recent first, paginated with offset and limit
+func (s *Server) handleListDeadJobs(c *gin.Context) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit, use 1 to 500"})
+		return
+	}
+
+	jobs, total, err := s.jobQueue.ListDeadJobs(c.Request.Context(), offset, limit)
+	if err != nil {
+		s.logger.Error("Failed to list dead jobs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list dead jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":  jobs,
+		"count": len(jobs),
+		"total": total,
+	})
+}
+
+func (s *Server) handleGetDeadJob(c *gin.Context) {
+	job, err := s.jobQueue.GetDeadJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		s.respondDeadJobError(c, "read", err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (s *Server) handleReplayDeadJob(c *gin.Context) {
+	job, err := s.jobQueue.ReplayDeadJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		s.respondDeadJobError(c, "replay", err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Job queued",
+		"job_id":  job.ID,
+	})
+}
+
+func (s *Server) handleDeleteDeadJob(c *gin.Context) {
+	deleted, err := s.jobQueue.DeleteDeadJob(c.Request.Context(), c.Param("id"))
+	if err == nil && !deleted {
+		err = queue.ErrDeadJobNotFound
+	}
+	if err != nil {
+		s.respondDeadJobError(c, "delete", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) handlePurgeDeadJobs(c *gin.Context) {
+	count, err := s.jobQueue.PurgeDeadJobs(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to purge dead jobs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge dead jobs"})
+		return
+	}
+	s.logger.Info("Purged dead jobs", "count", count)
+	c.JSON(http.StatusOK, gin.H{"purged": count})
+}
+
+func (s *Server) respondDeadJobError(c *gin.Context, action string, err error) {
+	if errors.Is(err, queue.ErrDeadJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead job not found"})
+		return
+	}
+	s.logger.Error("Failed to "+action+" dead job", "jobId", c.Param("id"), "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to %s dead job", action)})
+}
+
+// HandleDeadJob implements queue.DeadJobHandler, telling the author of the
+// merge request that its check could not be completed when enabled
+func (s *Server) HandleDeadJob(c context.Context, job *queue.WebhookJob) {
+	if !s.config.Queue.Queue.NotifyDeadJobs {
+		return
+	}
+
+	mrID, err := strconv.Atoi(job.MergeRequestIID)
+	if err != nil {
+		s.logger.Error("Invalid merge request of dead job", "jobId", job.ID, "mrId", job.MergeRequestIID, "error", err)
+		return
+	}
+
+	note := fmt.Sprintf(deadJobNote, job.Attempts)
+	if err := s.gitlabClient.CreateMergeRequestNote(c, job.ProjectID, mrID, note); err != nil {
+		s.logger.Error("Failed to notify about dead job",
+			"jobId", job.ID,
+			"projectId", job.ProjectID,
+			"mrId", job.MergeRequestIID,
+			"error", err)
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/internal/queue"
+	"gitlab-mr-conformity-bot/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{name: "disabled without token", token: "", authorization: "Bearer secret", status: http.StatusForbidden},
+		{name: "missing header", token: "secret", status: http.StatusUnauthorized},
+		{name: "not a bearer token", token: "secret", authorization: "secret", status: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", authorization: "Bearer other", status: http.StatusUnauthorized},
+		{name: "valid token", token: "secret", authorization: "Bearer secret", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobQueue, err := queue.NewMemoryQueue(nil, logger.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := &config.Config{}
+			cfg.Server.AdminToken = tt.token
+			cfg.Queue.Enabled = true
+			srv := &Server{config: cfg, jobQueue: jobQueue, logger: logger.New()}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/dead-jobs", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			srv.Router().ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+		})
+	}
+}
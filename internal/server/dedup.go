@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/metrics"
+	"gitlab-mr-conformity-bot/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventUUIDHeader identifies a webhook delivery; GitLab sends the same value when it retries
+const eventUUIDHeader = "X-Gitlab-Event-UUID"
+
+// deliveryStore records the webhook deliveries that were already accepted
+type deliveryStore interface {
+	// MarkDelivery records a delivery for ttl and reports whether it was not seen before
+	MarkDelivery(c context.Context, deliveryID string, ttl time.Duration) (bool, error)
+	// ForgetDelivery removes a delivery, so a retry of it is processed again
+	ForgetDelivery(c context.Context, deliveryID string) error
+}
+
+// storageDeliveries records deliveries in a storage.Storage, for running without the queue
+type storageDeliveries struct {
+	storage storage.Storage
+}
+
+func deliveryKey(deliveryID string) string {
+	return "webhook:event:" + deliveryID
+}
+
+func (sd storageDeliveries) MarkDelivery(_ context.Context, deliveryID string, ttl time.Duration) (bool, error) {
+	return sd.storage.SetIfNotExists(deliveryKey(deliveryID), time.Now().Unix(), ttl)
+}
+
+func (sd storageDeliveries) ForgetDelivery(_ context.Context, deliveryID string) error {
+	return sd.storage.Delete(deliveryKey(deliveryID))
+}
+
+// claimDelivery records the delivery of the current request and reports
+// whether it should be processed. Requests without an event UUID are always
+// processed, and so are deliveries that cannot be recorded: a duplicate check
+// is preferred over a dropped one.
+func (s *Server) claimDelivery(c *gin.Context, event string) (string, bool) {
+	deliveryID := strings.TrimSpace(c.Request.Header.Get(eventUUIDHeader))
+	if s.deliveries == nil || deliveryID == "" {
+		return "", true
+	}
+
+	isNew, err := s.deliveries.MarkDelivery(c.Request.Context(), deliveryID, s.config.Webhook.DedupTTL)
+	if err != nil {
+		s.logger.Warn("Failed to record webhook delivery", "deliveryId", deliveryID, "error", err)
+		return "", true
+	}
+	if !isNew {
+		metrics.WebhookDuplicates.WithLabelValues(event).Inc()
+		s.logger.Info("Ignoring duplicate webhook delivery", "deliveryId", deliveryID, "event", event)
+		return deliveryID, false
+	}
+	return deliveryID, true
+}
+
+// releaseDelivery forgets a delivery that failed, so GitLab's retry is processed
+func (s *Server) releaseDelivery(c *gin.Context, deliveryID string) {
+	if s.deliveries == nil || deliveryID == "" {
+		return
+	}
+	if err := s.deliveries.ForgetDelivery(c.Request.Context(), deliveryID); err != nil {
+		s.logger.Warn("Failed to release webhook delivery", "deliveryId", deliveryID, "error", err)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"strconv"
 	"strings"
 
+	"gitlab-mr-conformity-bot/internal/conformity"
+
 	"github.com/gin-gonic/gin"
 	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Webhook struct {
@@ -16,6 +20,16 @@ type Webhook struct {
 }
 
 func (s *Server) handleHealth(c *gin.Context) {
+	if err := s.storage.Ping(); err != nil {
+		s.logger.Error("Storage is unavailable", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unavailable",
+			"service": "gitlab-mr-conform",
+			"error":   "Storage is unavailable",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "ok",
 		"service": "gitlab-mr-conform",
@@ -53,6 +67,7 @@ func (s *Server) handleWebhookNoQueue(c *gin.Context) {
 	}
 
 	eventType := gitlabapi.EventType(event)
+	observeWebhook(eventType, wh.EventsToAccept)
 	if !isEventSubscribed(eventType, wh.EventsToAccept) {
 		s.logger.Error("Event not defined to be parsed", "error", eventType)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Event not defined to be parsed"})
@@ -67,27 +82,46 @@ func (s *Server) handleWebhookNoQueue(c *gin.Context) {
 		return
 	}
 
+	deliveryID, process := s.claimDelivery(c, event)
+	if !process {
+		c.JSON(http.StatusOK, gin.H{"message": "Duplicate delivery ignored"})
+		return
+	}
+
 	switch parsedEvent := parsedEvent.(type) {
 	case *gitlabapi.MergeEvent:
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(
+			attribute.String("gitlab.event", string(eventType)),
+			attribute.Int("gitlab.project_id", parsedEvent.Project.ID),
+			attribute.Int("gitlab.merge_request_iid", parsedEvent.ObjectAttributes.IID),
+		)
 		s.logger.Info("Processing merge request event",
 			"project_id", parsedEvent.Project.ID,
 			"mr_id", parsedEvent.ObjectAttributes.IID,
 			"action", parsedEvent.ObjectAttributes.Action)
 
+		// GitLab stops waiting for the response long before a check completes,
+		// so the check is bounded by its own timeout and the server shutdown
+		// rather than by the request
+		ctx, done := s.startCheck(c.Request.Context())
+		defer done()
+
 		// Check merge request conformity
-		result, err := s.checker.CheckMergeRequest(parsedEvent.Project.ID, parsedEvent.ObjectAttributes.IID)
+		result, err := s.checker.CheckMergeRequest(ctx, parsedEvent.Project.ID, parsedEvent.ObjectAttributes.IID)
 		if err != nil {
 			s.logger.Error("Failed to check merge request",
 				"project_id", parsedEvent.Project.ID,
 				"mr_id", parsedEvent.ObjectAttributes.IID,
 				"error", err)
+			s.releaseDelivery(c, deliveryID)
 			c.JSON(http.StatusOK, gin.H{"error": "Check failed"})
 			return
 		}
 
 		// Post discussion with results
-		if err := s.gitlabClient.CreateUpdateMergeRequestDiscussion(parsedEvent.Project.ID, parsedEvent.ObjectAttributes.IID, result.Summary, result.Passed); err != nil {
+		if err := s.gitlabClient.CreateUpdateMergeRequestDiscussion(ctx, parsedEvent.Project.ID, parsedEvent.ObjectAttributes.IID, result.Summary, result.Passed); err != nil {
 			s.logger.Error("Failed to post discussion", "error", err)
+			s.releaseDelivery(c, deliveryID)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post discussion"})
 			return
 		}
@@ -98,7 +132,7 @@ func (s *Server) handleWebhookNoQueue(c *gin.Context) {
 			status = "failed"
 		}
 
-		if err := s.gitlabClient.SetCommitStatus(parsedEvent.Project.ID, parsedEvent.ObjectAttributes.LastCommit.ID, status, "MR Conformity Check"); err != nil {
+		if err := s.gitlabClient.SetCommitStatus(ctx, parsedEvent.Project.ID, parsedEvent.ObjectAttributes.LastCommit.ID, status, "MR Conformity Check"); err != nil {
 			s.logger.Error("Failed to set commit status", "error", err)
 		}
 
@@ -120,7 +154,10 @@ func (s *Server) handleStatus(c *gin.Context) {
 		return
 	}
 
-	result, err := s.checker.CheckMergeRequest(projectID, mrID)
+	// Cached results are served unless a refresh is requested
+	opts := conformity.CheckOptions{Refresh: c.Query("refresh") == "true"}
+
+	result, err := s.checker.CheckMergeRequestWithOptions(c.Request.Context(), projectID, mrID, opts)
 	if err != nil {
 		s.logger.Error("Failed to check merge request", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Check failed"})
@@ -131,5 +168,23 @@ func (s *Server) handleStatus(c *gin.Context) {
 		"passed":   result.Passed,
 		"failures": result.Failures,
 		"summary":  result.Summary,
+		"cached":   result.Cached,
+	})
+}
+
+func (s *Server) handleConfig(c *gin.Context) {
+	projectID := c.Param("project_id")
+
+	effective, err := s.checker.EffectiveConfig(c.Request.Context(), projectID)
+	if err != nil {
+		s.logger.Error("Failed to load effective configuration", "projectId", projectID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load configuration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"layers":  effective.Layers,
+		"rules":   effective.Values,
+		"sources": effective.Sources,
 	})
 }
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/history"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleHistory returns the recorded check runs of a project, optionally of a
+// single merge request, as JSON or as JSON Lines with format=jsonl
+func (s *Server) handleHistory(c *gin.Context) {
+	if s.history == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "History is disabled"})
+		return
+	}
+
+	filter, err := s.historyFilter(c, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := s.history.Query(filter)
+	if err != nil {
+		s.logger.Error("Failed to query history", "projectId", filter.ProjectID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query history"})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{
+			"records": records,
+			"count":   len(records),
+		})
+	case "jsonl":
+		filename := fmt.Sprintf("mr-conform-history-%d.jsonl", filter.ProjectID)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		if err := history.WriteJSONLines(c.Writer, records); err != nil {
+			s.logger.Error("Failed to export history", "projectId", filter.ProjectID, "error", err)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, use json or jsonl"})
+	}
+}
+
+// historyFilter reads the history query parameters. The project may be given
+// by ID or path; paths are resolved to the ID records are stored under.
+func (s *Server) historyFilter(c *gin.Context, requireProject bool) (history.Filter, error) {
+	var filter history.Filter
+	var err error
+
+	if project := c.Query("project"); project != "" {
+		if filter.ProjectID, err = strconv.Atoi(project); err != nil {
+			p, err := s.gitlabClient.GetProject(c.Request.Context(), project)
+			if err != nil {
+				return filter, fmt.Errorf("unknown project %q", project)
+			}
+			filter.ProjectID = p.ID
+		}
+	} else if requireProject {
+		return filter, fmt.Errorf("project is required")
+	}
+
+	if mr := c.Query("mr"); mr != "" {
+		if filter.MergeRequestIID, err = strconv.Atoi(mr); err != nil {
+			return filter, fmt.Errorf("invalid mr %q", mr)
+		}
+	}
+	if since := c.Query("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, fmt.Errorf("invalid since %q, expected RFC 3339", since)
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return filter, fmt.Errorf("invalid until %q, expected RFC 3339", until)
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("invalid limit %q", limit)
+		}
+	}
+
+	return filter, nil
+}
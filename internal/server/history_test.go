@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/internal/history"
+	"gitlab-mr-conformity-bot/internal/storage"
+	"gitlab-mr-conformity-bot/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHistoryEndpointsRequireAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{name: "disabled without token", token: "", authorization: "Bearer secret", status: http.StatusForbidden},
+		{name: "missing header", token: "secret", status: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", authorization: "Bearer other", status: http.StatusUnauthorized},
+		{name: "valid token", token: "secret", authorization: "Bearer secret", status: http.StatusOK},
+	}
+
+	for _, path := range []string{"/api/v1/history?project=1", "/api/v1/reports/authors", "/api/v1/reports/rules?format=csv"} {
+		for _, tt := range tests {
+			t.Run(path+" "+tt.name, func(t *testing.T) {
+				cfg := &config.Config{}
+				cfg.Server.AdminToken = tt.token
+				srv := &Server{config: cfg, history: history.NewStore(storage.NewMemoryStorage(), 0), logger: logger.New()}
+
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.authorization != "" {
+					req.Header.Set("Authorization", tt.authorization)
+				}
+				rec := httptest.NewRecorder()
+				srv.Router().ServeHTTP(rec, req)
+
+				if rec.Code != tt.status {
+					t.Errorf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+				}
+			})
+		}
+	}
+}
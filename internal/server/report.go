@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"gitlab-mr-conformity-bot/internal/history"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleRuleReport(c *gin.Context) {
+	s.serveFailureRates(c, "rules", "rule", history.RuleFailureRates)
+}
+
+func (s *Server) handleProjectReport(c *gin.Context) {
+	s.serveFailureRates(c, "projects", "project_id", history.ProjectFailureRates)
+}
+
+func (s *Server) handleAuthorReport(c *gin.Context) {
+	s.serveFailureRates(c, "authors", "author", history.AuthorFailureRates)
+}
+
+func (s *Server) handleTimeToGreenReport(c *gin.Context) {
+	records, ok := s.reportRecords(c)
+	if !ok {
+		return
+	}
+
+	times, summary := history.TimesToGreen(records)
+	if c.Query("format") == "csv" {
+		s.serveCSV(c, "time-to-green", func(c *gin.Context) error {
+			return history.WriteTimesToGreenCSV(c.Writer, times)
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary":        summary,
+		"merge_requests": times,
+	})
+}
+
+// serveFailureRates aggregates the selected records with aggregate and
+// serves them as JSON, or as CSV with format=csv
+func (s *Server) serveFailureRates(c *gin.Context, report, keyName string, aggregate func([]history.Record) []history.FailureRate) {
+	records, ok := s.reportRecords(c)
+	if !ok {
+		return
+	}
+
+	rates := aggregate(records)
+	if c.Query("format") == "csv" {
+		s.serveCSV(c, report, func(c *gin.Context) error {
+			return history.WriteFailureRatesCSV(c.Writer, keyName, rates)
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"checks": len(records),
+		"rates":  rates,
+	})
+}
+
+// reportRecords returns the records selected by the query parameters, of all
+// projects unless project is given; it writes the error response when it fails
+func (s *Server) reportRecords(c *gin.Context) ([]history.Record, bool) {
+	if s.history == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "History is disabled"})
+		return nil, false
+	}
+
+	filter, err := s.historyFilter(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	records, err := s.history.Query(filter)
+	if err != nil {
+		s.logger.Error("Failed to query history", "projectId", filter.ProjectID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query history"})
+		return nil, false
+	}
+	return records, true
+}
+
+func (s *Server) serveCSV(c *gin.Context, name string, write func(c *gin.Context) error) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "mr-conform-"+name+".csv"))
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+	if err := write(c); err != nil {
+		s.logger.Error("Failed to export report", "report", name, "error", err)
+	}
+}
@@ -1,9 +1,14 @@
 package server
 
 import (
+	"context"
+	"sync"
+
 	"gitlab-mr-conformity-bot/internal/config"
 	"gitlab-mr-conformity-bot/internal/conformity"
 	"gitlab-mr-conformity-bot/internal/gitlab"
+	"gitlab-mr-conformity-bot/internal/history"
+	"gitlab-mr-conformity-bot/internal/metrics"
 	"gitlab-mr-conformity-bot/internal/queue"
 	"gitlab-mr-conformity-bot/internal/storage"
 	"gitlab-mr-conformity-bot/pkg/logger"
@@ -16,19 +21,41 @@ type Server struct {
 	gitlabClient *gitlab.Client
 	checker      *conformity.Checker
 	storage      storage.Storage
+	history      *history.Store
 	logger       *logger.Logger
-	queueManager *queue.QueueManager
+	jobQueue     queue.JobQueue
+	deliveries   deliveryStore
+
+	// checks tracks the checks run within webhook requests while the queue
+	// is disabled; cancelChecks cancels them once draining them timed out
+	checks       sync.WaitGroup
+	checksCtx    context.Context
+	cancelChecks context.CancelFunc
 }
 
-func NewServer(cfg *config.Config, client *gitlab.Client, checker *conformity.Checker, store storage.Storage, log *logger.Logger, queueManager *queue.QueueManager) *Server {
-	return &Server{
+func NewServer(cfg *config.Config, client *gitlab.Client, checker *conformity.Checker, store storage.Storage, log *logger.Logger, jobQueue queue.JobQueue) *Server {
+	srv := &Server{
 		config:       cfg,
 		gitlabClient: client,
 		checker:      checker,
 		storage:      store,
 		logger:       log,
-		queueManager: queueManager,
+		jobQueue:     jobQueue,
+	}
+	srv.checksCtx, srv.cancelChecks = context.WithCancel(context.Background())
+	if cfg.Webhook.DedupTTL > 0 {
+		// Share deliveries through the queue backend if it can hold them
+		if shared, ok := jobQueue.(deliveryStore); ok && cfg.Queue.Enabled {
+			srv.deliveries = shared
+		} else {
+			srv.deliveries = storageDeliveries{storage: store}
+		}
 	}
+	if checker != nil {
+		// Query the history the checker records to
+		srv.history = checker.History()
+	}
+	return srv
 }
 
 func (s *Server) Router() *gin.Engine {
@@ -37,11 +64,16 @@ func (s *Server) Router() *gin.Engine {
 	}
 
 	router := gin.New()
-	router.Use(gin.Logger(), gin.Recovery())
+	router.Use(gin.Logger(), gin.Recovery(), traceRequests())
 
 	// Health check
 	router.GET("/health", s.handleHealth)
 
+	// Prometheus metrics
+	if s.config.Metrics.Enabled {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
 	// Webhook endpoint
 
 	if s.config.Queue.Enabled {
@@ -53,5 +85,29 @@ func (s *Server) Router() *gin.Engine {
 	// Status endpoint
 	router.GET("/status/:project_id/:mr_id", s.handleStatus)
 
+	// Effective configuration endpoint
+	router.GET("/config/:project_id", s.handleConfig)
+
+	// Check history endpoint, protected by the admin token as it exposes
+	// authors and results of every checked merge request
+	api := router.Group("/api/v1")
+	api.GET("/history", s.requireAdmin(), s.handleHistory)
+
+	// Compliance reports aggregated from the check history, protected like the history
+	reports := api.Group("/reports", s.requireAdmin())
+	reports.GET("/rules", s.handleRuleReport)
+	reports.GET("/projects", s.handleProjectReport)
+	reports.GET("/authors", s.handleAuthorReport)
+	reports.GET("/time-to-green", s.handleTimeToGreenReport)
+
+	// Admin endpoints, protected by the admin token
+	admin := api.Group("/admin", s.requireAdmin())
+	deadJobs := admin.Group("/dead-jobs", s.requireQueue)
+	deadJobs.GET("", s.handleListDeadJobs)
+	deadJobs.DELETE("", s.handlePurgeDeadJobs)
+	deadJobs.GET("/:id", s.handleGetDeadJob)
+	deadJobs.POST("/:id/replay", s.handleReplayDeadJob)
+	deadJobs.DELETE("/:id", s.handleDeleteDeadJob)
+
 	return router
 }
@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"gitlab-mr-conformity-bot/internal/gitlab"
+	"gitlab-mr-conformity-bot/internal/metrics"
 	"gitlab-mr-conformity-bot/internal/queue"
 	"io"
 	"net/http"
@@ -12,6 +15,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	gitlabapi "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // HandleWebhook processes incoming webhook and enqueues it for processing
@@ -46,6 +51,7 @@ func (s *Server) HandleWebhook(c *gin.Context) {
 	}
 
 	eventType := gitlabapi.EventType(event)
+	observeWebhook(eventType, wh.EventsToAccept)
 	if !isEventSubscribed(eventType, wh.EventsToAccept) {
 		s.logger.Error("Event not defined to be parsed", "error", eventType)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Event not defined to be parsed"})
@@ -60,8 +66,19 @@ func (s *Server) HandleWebhook(c *gin.Context) {
 		return
 	}
 
+	deliveryID, process := s.claimDelivery(c, event)
+	if !process {
+		c.JSON(http.StatusOK, gin.H{"message": "Duplicate delivery ignored"})
+		return
+	}
+
 	switch parsedEvent := parsedEvent.(type) {
 	case *gitlabapi.MergeEvent:
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(
+			attribute.String("gitlab.event", string(eventType)),
+			attribute.Int("gitlab.project_id", parsedEvent.Project.ID),
+			attribute.Int("gitlab.merge_request_iid", parsedEvent.ObjectAttributes.IID),
+		)
 		s.logger.Info("Processing merge request event",
 			"projectId", parsedEvent.Project.ID,
 			"mrId", parsedEvent.ObjectAttributes.IID,
@@ -70,9 +87,17 @@ func (s *Server) HandleWebhook(c *gin.Context) {
 		pID := strconv.Itoa(parsedEvent.Project.ID)
 		mrID := strconv.Itoa(parsedEvent.ObjectAttributes.IID)
 		// Enqueue the webhook for processing
-		jobID, err := s.queueManager.EnqueueWebhook(c, pID, mrID, parsedEvent.EventType, parsedEvent)
+		jobID, err := s.jobQueue.EnqueueWebhook(c.Request.Context(), pID, mrID, parsedEvent.EventType, parsedEvent)
+		if errors.Is(err, queue.ErrQueueFull) {
+			s.logger.Warn("Rejected webhook event, queue is full", "projectId", pID, "mrId", mrID)
+			s.releaseDelivery(c, deliveryID)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Queue is full"})
+			return
+		}
 		if err != nil {
 			s.logger.Error("Failed to enqueue webhook event", "error", err)
+			s.releaseDelivery(c, deliveryID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enqueue webhook event"})
 			return
 		}
 		//log.Printf("Webhook enqueued successfully with job ID: %s", jobID)
@@ -88,28 +113,28 @@ func (s *Server) ProcessJob(c context.Context, job *queue.WebhookJob) error {
 
 	mrID, err := strconv.Atoi(job.MergeRequestIID)
 	if err != nil {
-		fmt.Println("Error converting string to int:", err)
+		return queue.Permanent(fmt.Errorf("invalid merge request IID %q: %w", job.MergeRequestIID, err))
 	}
 
 	// Check merge request conformity
-	result, err := s.checker.CheckMergeRequest(job.ProjectID, mrID)
+	result, err := s.checker.CheckMergeRequest(c, job.ProjectID, mrID)
 	if err != nil {
 		s.logger.Error("Failed to check merge request",
 			"jobId", job.ID,
 			"projectId", job.ProjectID,
 			"mrId", job.MergeRequestIID,
 			"error", err)
-		return err
+		return classifyJobError(err)
 	}
 
 	// Post discussion with results
-	if err := s.gitlabClient.CreateUpdateMergeRequestDiscussion(job.ProjectID, mrID, result.Summary, result.Passed); err != nil {
+	if err := s.gitlabClient.CreateUpdateMergeRequestDiscussion(c, job.ProjectID, mrID, result.Summary, result.Passed); err != nil {
 		s.logger.Error("Failed to post discussion",
 			"jobId", job.ID,
 			"projectId", job.ProjectID,
 			"mrId", job.MergeRequestIID,
 			"error", err)
-		return err
+		return classifyJobError(err)
 	}
 
 	// Set commit status
@@ -118,40 +143,101 @@ func (s *Server) ProcessJob(c context.Context, job *queue.WebhookJob) error {
 		status = "failed"
 	}
 
-	if err := s.gitlabClient.SetCommitStatus(job.ProjectID, job.Payload.ObjectAttributes.LastCommit.ID, status, "MR Conformity Check"); err != nil {
+	if err := s.gitlabClient.SetCommitStatus(c, job.ProjectID, job.Payload.ObjectAttributes.LastCommit.ID, status, "MR Conformity Check"); err != nil {
 		s.logger.Error("Failed to set commit status",
 			"jobId", job.ID,
 			"projectId", job.ProjectID,
 			"mrId", job.MergeRequestIID,
 			"error", err)
-		return err
+		return classifyJobError(err)
 	}
 
 	return nil
 }
 
+// classifyJobError marks errors that a retry would get again as non-retryable,
+// so the job fails right away instead of using up its attempts
+func classifyJobError(err error) error {
+	if gitlab.IsPermanentStatus(err) {
+		return queue.Permanent(err)
+	}
+	return err
+}
+
 // StartProcessor starts the background job processor
 func (s *Server) StartProcessor(c context.Context) {
 	s.logger.Info("Starting webhook processor")
-	s.queueManager.StartProcessor(c, s)
+	s.jobQueue.StartProcessor(c, s)
 }
 
-// StopProcessor stops the background job processor
-func (s *Server) StopProcessor() {
+// StopProcessor stops the background job processor, waiting for jobs in flight until c is done
+func (s *Server) StopProcessor(c context.Context) error {
 	s.logger.Info("Stopping webhook processor...")
-	s.queueManager.StopProcessor()
+	return s.jobQueue.StopProcessor(c)
+}
+
+// startCheck returns a context for a check run within a request, with the
+// values of c but cancelled only by DrainChecks, and a function to call once
+// the check finished
+func (s *Server) startCheck(c context.Context) (context.Context, func()) {
+	s.checks.Add(1)
+	ctx, cancel := context.WithCancel(context.WithoutCancel(c))
+	stop := context.AfterFunc(s.checksCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		s.checks.Done()
+	}
+}
+
+// DrainChecks waits for the checks run within webhook requests to finish.
+// When c is done first, they are cancelled and c's error returned.
+func (s *Server) DrainChecks(c context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.checks.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-c.Done():
+		err = c.Err()
+		s.logger.Warn("Cancelling checks in flight", "error", err)
+		s.cancelChecks()
+		<-done
+	}
+	s.cancelChecks()
+	return err
 }
 
 // Health check methods
 
 func (s *Server) Health(c context.Context) error {
-	return s.queueManager.Health(c)
+	if s.jobQueue == nil {
+		return nil
+	}
+	return s.jobQueue.Health(c)
 }
 
 func (s *Server) GetStats(c context.Context) (*queue.QueueStats, error) {
-	return s.queueManager.GetQueueStats(c)
+	if s.jobQueue == nil {
+		return &queue.QueueStats{}, nil
+	}
+	return s.jobQueue.GetQueueStats(c)
 }
 
 func isEventSubscribed(event gitlabapi.EventType, events []gitlabapi.EventType) bool {
 	return slices.Contains(events, event)
 }
+
+// observeWebhook counts a received webhook; events that are not accepted are
+// counted as "other" to bound the number of label values
+func observeWebhook(event gitlabapi.EventType, accepted []gitlabapi.EventType) {
+	label := "other"
+	if isEventSubscribed(event, accepted) {
+		label = string(event)
+	}
+	metrics.WebhooksReceived.WithLabelValues(label).Inc()
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/config"
+	"gitlab-mr-conformity-bot/pkg/logger"
+)
+
+func TestDrainChecks_WaitsForChecks(t *testing.T) {
+	srv := NewServer(&config.Config{}, nil, nil, nil, logger.New(), nil)
+	ctx, done := srv.startCheck(context.Background())
+
+	drained := make(chan error, 1)
+	go func() { drained <- srv.DrainChecks(context.Background()) }()
+
+	select {
+	case <-drained:
+		t.Fatal("expected DrainChecks to wait for the check")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if ctx.Err() != nil {
+		t.Errorf("expected the check not to be cancelled, got %v", ctx.Err())
+	}
+	done()
+	if err := <-drained; err != nil {
+		t.Errorf("expected a clean drain, got %v", err)
+	}
+}
+
+func TestDrainChecks_CancelsChecksAtDeadline(t *testing.T) {
+	srv := NewServer(&config.Config{}, nil, nil, nil, logger.New(), nil)
+
+	// The request of the check is gone, the check goes on
+	request, cancelRequest := context.WithCancel(context.Background())
+	ctx, done := srv.startCheck(request)
+	cancelRequest()
+	if ctx.Err() != nil {
+		t.Fatalf("expected the check to outlive its request, got %v", ctx.Err())
+	}
+	go func() {
+		<-ctx.Done()
+		done()
+	}()
+
+	c, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.DrainChecks(c); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the drain deadline to pass, got %v", err)
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("expected the check to be cancelled, got %v", ctx.Err())
+	}
+}
@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltBucket = []byte("storage")
+	// boltListsBucket holds a nested bucket per list, keyed by sequence number
+	boltListsBucket = []byte("lists")
+)
+
+// boltHeaderSize is the size of the expiry stored in front of every value
+const boltHeaderSize = 8
+
+// BoltStorage stores values in an embedded BoltDB file. Each value is prefixed
+// with its expiry in Unix nanoseconds, 0 meaning it never expires; expired
+// values are removed periodically.
+type BoltStorage struct {
+	db       *bolt.DB
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewBoltStorage opens or creates the database at path and removes expired
+// values every cleanupInterval, 0 disables the cleanup
+func NewBoltStorage(path string, cleanupInterval time.Duration) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltBucket, boltListsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	b := &BoltStorage{db: db, stopChan: make(chan struct{})}
+	if cleanupInterval > 0 {
+		b.wg.Add(1)
+		go b.cleanup(cleanupInterval)
+	}
+	return b, nil
+}
+
+func (b *BoltStorage) Set(key string, value interface{}) error {
+	return b.SetWithTTL(key, value, 0)
+}
+
+func (b *BoltStorage) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	record, err := boltRecord(value, ttl)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), record)
+	})
+}
+
+func (b *BoltStorage) SetIfNotExists(key string, value interface{}, ttl time.Duration) (bool, error) {
+	record, err := boltRecord(value, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	stored := false
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		if existing := bucket.Get([]byte(key)); existing != nil && !boltExpired(existing, time.Now()) {
+			return nil
+		}
+		stored = true
+		return bucket.Put([]byte(key), record)
+	})
+	return stored && err == nil, err
+}
+
+// boltRecord encodes a value with its expiry
+func boltRecord(value interface{}, ttl time.Duration) ([]byte, error) {
+	data, err := encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixNano()
+	}
+	record := make([]byte, boltHeaderSize+len(data))
+	binary.BigEndian.PutUint64(record, uint64(expires))
+	copy(record[boltHeaderSize:], data)
+	return record, nil
+}
+
+func (b *BoltStorage) Get(key string) (interface{}, error) {
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		record := tx.Bucket(boltBucket).Get([]byte(key))
+		if record == nil || boltExpired(record, time.Now()) {
+			return nil
+		}
+		// Records are only valid within the transaction
+		value = append([]byte{}, record[boltHeaderSize:]...)
+		return nil
+	})
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (b *BoltStorage) Delete(key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		lists := tx.Bucket(boltListsBucket)
+		if lists.Bucket([]byte(key)) != nil {
+			if err := lists.DeleteBucket([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+}
+
+func (b *BoltStorage) Exists(key string) bool {
+	exists := false
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		record := tx.Bucket(boltBucket).Get([]byte(key))
+		exists = record != nil && !boltExpired(record, time.Now())
+		if !exists {
+			exists = tx.Bucket(boltListsBucket).Bucket([]byte(key)) != nil
+		}
+		return nil
+	})
+	return exists
+}
+
+func (b *BoltStorage) Append(key string, value interface{}) error {
+	data, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		list, err := tx.Bucket(boltListsBucket).CreateBucketIfNotExists([]byte(key))
+		if err != nil {
+			return err
+		}
+		seq, err := list.NextSequence()
+		if err != nil {
+			return err
+		}
+		// Big-endian sequence numbers keep the list in insertion order
+		id := make([]byte, 8)
+		binary.BigEndian.PutUint64(id, seq)
+		return list.Put(id, data)
+	})
+}
+
+func (b *BoltStorage) List(key string) ([]interface{}, error) {
+	var values []interface{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		list := tx.Bucket(boltListsBucket).Bucket([]byte(key))
+		if list == nil {
+			return nil
+		}
+		return list.ForEach(func(_, data []byte) error {
+			values = append(values, append([]byte{}, data...))
+			return nil
+		})
+	})
+	return values, err
+}
+
+func (b *BoltStorage) ListReverse(key string, fn func(value interface{}) bool) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		list := tx.Bucket(boltListsBucket).Bucket([]byte(key))
+		if list == nil {
+			return nil
+		}
+		cursor := list.Cursor()
+		for id, data := cursor.Last(); id != nil; id, data = cursor.Prev() {
+			if !fn(append([]byte{}, data...)) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltStorage) Trim(key string, keep int) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		list := tx.Bucket(boltListsBucket).Bucket([]byte(key))
+		if list == nil {
+			return nil
+		}
+		// Only the oldest values are ever removed, so the sequence numbers
+		// stay contiguous and the newest one tells which values to drop
+		// without counting the whole list
+		cursor := list.Cursor()
+		last, _ := cursor.Last()
+		if last == nil || binary.BigEndian.Uint64(last) <= uint64(max(keep, 0)) {
+			return nil
+		}
+		cutoff := binary.BigEndian.Uint64(last) - uint64(max(keep, 0))
+		// Collect the oldest keys first, deleting while iterating skips keys
+		var ids [][]byte
+		for id, _ := cursor.First(); id != nil && binary.BigEndian.Uint64(id) <= cutoff; id, _ = cursor.Next() {
+			ids = append(ids, append([]byte{}, id...))
+		}
+		for _, id := range ids {
+			if err := list.Delete(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltStorage) Ping() error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltBucket) == nil {
+			return fmt.Errorf("bucket %s not found", boltBucket)
+		}
+		return nil
+	})
+}
+
+func (b *BoltStorage) Close() error {
+	close(b.stopChan)
+	b.wg.Wait()
+	return b.db.Close()
+}
+
+// cleanup periodically removes expired values until the storage is closed
+func (b *BoltStorage) cleanup(interval time.Duration) {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopChan:
+			return
+		case <-ticker.C:
+			_ = b.removeExpired()
+		}
+	}
+}
+
+func (b *BoltStorage) removeExpired() error {
+	now := time.Now()
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		// Collect the expired keys first, deleting while iterating skips keys
+		var expired [][]byte
+		cursor := bucket.Cursor()
+		for key, record := cursor.First(); key != nil; key, record = cursor.Next() {
+			if boltExpired(record, now) {
+				expired = append(expired, append([]byte{}, key...))
+			}
+		}
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func boltExpired(record []byte, now time.Time) bool {
+	if len(record) < boltHeaderSize {
+		return true
+	}
+	expires := int64(binary.BigEndian.Uint64(record))
+	return expires != 0 && now.UnixNano() > expires
+}
@@ -2,46 +2,145 @@ package storage
 
 import (
 	"sync"
+	"time"
 )
 
+// memorySweepInterval is the number of writes after which expired entries are removed
+const memorySweepInterval = 1024
+
 type MemoryStorage struct {
-	data map[string]interface{}
-	mu   sync.RWMutex
+	data   map[string]memoryEntry
+	lists  map[string][]interface{}
+	writes int
+	mu     sync.RWMutex
+}
+
+type memoryEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func (e memoryEntry) expired() bool {
+	return !e.expires.IsZero() && time.Now().After(e.expires)
 }
 
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		data: make(map[string]interface{}),
+		data:  make(map[string]memoryEntry),
+		lists: make(map[string][]interface{}),
 	}
 }
 
 func (m *MemoryStorage) Set(key string, value interface{}) error {
+	return m.SetWithTTL(key, value, 0)
+}
+
+func (m *MemoryStorage) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.data[key] = value
+	m.set(key, value, ttl)
 	return nil
 }
 
+func (m *MemoryStorage) SetIfNotExists(key string, value interface{}, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, exists := m.data[key]; exists && !entry.expired() {
+		return false, nil
+	}
+	m.set(key, value, ttl)
+	return true, nil
+}
+
+// set stores a value, the caller must hold the write lock
+func (m *MemoryStorage) set(key string, value interface{}, ttl time.Duration) {
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	m.data[key] = entry
+
+	m.writes++
+	if m.writes >= memorySweepInterval {
+		m.writes = 0
+		m.sweep()
+	}
+}
+
 func (m *MemoryStorage) Get(key string) (interface{}, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	value, exists := m.data[key]
-	if !exists {
+	entry, exists := m.data[key]
+	if !exists || entry.expired() {
 		return nil, nil
 	}
-	return value, nil
+	return entry.value, nil
 }
 
 func (m *MemoryStorage) Delete(key string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.data, key)
+	delete(m.lists, key)
 	return nil
 }
 
 func (m *MemoryStorage) Exists(key string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	_, exists := m.data[key]
+	entry, exists := m.data[key]
+	if exists && !entry.expired() {
+		return true
+	}
+	_, exists = m.lists[key]
 	return exists
 }
+
+func (m *MemoryStorage) Append(key string, value interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lists[key] = append(m.lists[key], value)
+	return nil
+}
+
+func (m *MemoryStorage) List(key string) ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]interface{}{}, m.lists[key]...), nil
+}
+
+func (m *MemoryStorage) ListReverse(key string, fn func(value interface{}) bool) error {
+	values, _ := m.List(key)
+	for i := len(values) - 1; i >= 0; i-- {
+		if !fn(values[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStorage) Trim(key string, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if list := m.lists[key]; len(list) > keep {
+		m.lists[key] = append([]interface{}{}, list[len(list)-max(keep, 0):]...)
+	}
+	return nil
+}
+
+// sweep removes expired entries, the caller must hold the write lock
+func (m *MemoryStorage) sweep() {
+	for key, entry := range m.data {
+		if entry.expired() {
+			delete(m.data, key)
+		}
+	}
+}
+
+func (m *MemoryStorage) Ping() error {
+	return nil
+}
+
+func (m *MemoryStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/redisclient"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisTimeout bounds every Redis command, as the Storage interface takes no context
+const redisTimeout = 5 * time.Second
+
+// redisListPage is the number of list values ListReverse reads per command
+const redisListPage = 100
+
+// RedisConfig holds the connection settings of a Redis storage
+type RedisConfig struct {
+	Redis redisclient.Config
+	// KeyPrefix is prepended to every key, separating storage from queue keys
+	KeyPrefix string
+}
+
+// RedisStorage stores values in Redis, so they are shared between replicas
+type RedisStorage struct {
+	redis     redis.UniversalClient
+	keyPrefix string
+}
+
+func NewRedisStorage(config RedisConfig) (*RedisStorage, error) {
+	rdb, err := redisclient.New(config.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisStorage{
+		redis:     rdb,
+		keyPrefix: config.KeyPrefix,
+	}, nil
+}
+
+func (r *RedisStorage) Set(key string, value interface{}) error {
+	return r.SetWithTTL(key, value, 0)
+}
+
+func (r *RedisStorage) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	data, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.redis.Set(c, r.keyPrefix+key, data, ttl).Err()
+}
+
+func (r *RedisStorage) SetIfNotExists(key string, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.redis.SetNX(c, r.keyPrefix+key, data, ttl).Result()
+}
+
+func (r *RedisStorage) Get(key string) (interface{}, error) {
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	value, err := r.redis.Get(c, r.keyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (r *RedisStorage) Delete(key string) error {
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.redis.Del(c, r.keyPrefix+key).Err()
+}
+
+func (r *RedisStorage) Exists(key string) bool {
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	count, err := r.redis.Exists(c, r.keyPrefix+key).Result()
+	return err == nil && count > 0
+}
+
+func (r *RedisStorage) Append(key string, value interface{}) error {
+	data, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.redis.RPush(c, r.keyPrefix+key, data).Err()
+}
+
+func (r *RedisStorage) List(key string) ([]interface{}, error) {
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	items, err := r.redis.LRange(c, r.keyPrefix+key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		values = append(values, []byte(item))
+	}
+	return values, nil
+}
+
+// ListReverse reads the list in pages from its end. Values appended between
+// two pages shift the next page, so a value may be passed twice.
+func (r *RedisStorage) ListReverse(key string, fn func(value interface{}) bool) error {
+	for end := int64(-1); ; end -= redisListPage {
+		c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		items, err := r.redis.LRange(c, r.keyPrefix+key, end-redisListPage+1, end).Result()
+		cancel()
+		if err != nil {
+			return err
+		}
+		for i := len(items) - 1; i >= 0; i-- {
+			if !fn([]byte(items[i])) {
+				return nil
+			}
+		}
+		if int64(len(items)) < redisListPage {
+			return nil
+		}
+	}
+}
+
+func (r *RedisStorage) Trim(key string, keep int) error {
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if keep <= 0 {
+		return r.redis.Del(c, r.keyPrefix+key).Err()
+	}
+	return r.redis.LTrim(c, r.keyPrefix+key, int64(-keep), -1).Err()
+}
+
+func (r *RedisStorage) Ping() error {
+	c, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.redis.Ping(c).Err()
+}
+
+func (r *RedisStorage) Close() error {
+	return r.redis.Close()
+}
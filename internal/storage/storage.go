@@ -1,8 +1,50 @@
 package storage
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Storage is a key-value store for check results, caches and history.
+// Persistent backends keep []byte and string values as they are and encode
+// other values as JSON; their Get returns the stored bytes.
 type Storage interface {
 	Set(key string, value interface{}) error
+	// SetWithTTL stores a value that expires after ttl, a ttl of 0 never expires
+	SetWithTTL(key string, value interface{}, ttl time.Duration) error
+	// SetIfNotExists stores a value unless key exists, reporting whether it was stored
+	SetIfNotExists(key string, value interface{}, ttl time.Duration) (bool, error)
+	// Get returns the value of key, or nil when it does not exist or expired
 	Get(key string) (interface{}, error)
 	Delete(key string) error
 	Exists(key string) bool
+	// Append adds a value to the end of the list at key, creating it if needed
+	Append(key string, value interface{}) error
+	// List returns the values of the list at key in insertion order
+	List(key string) ([]interface{}, error)
+	// ListReverse calls fn with the values of the list at key, newest first,
+	// until fn returns false, so readers of recent values stop early
+	ListReverse(key string, fn func(value interface{}) bool) error
+	// Trim drops the oldest values of the list at key, keeping the last keep values
+	Trim(key string, keep int) error
+	// Ping reports whether the backend is reachable
+	Ping() error
+	Close() error
+}
+
+// encode converts a value to the bytes stored by persistent backends
+func encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	return data, nil
 }
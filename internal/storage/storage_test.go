@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"gitlab-mr-conformity-bot/internal/redisclient"
+
+	"github.com/alicebob/miniredis/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+func testStorage(t *testing.T, store Storage) {
+	t.Helper()
+
+	if err := store.Set("persistent", []byte("value")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.SetWithTTL("expiring", "value", 50*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+
+	if !store.Exists("persistent") || !store.Exists("expiring") {
+		t.Fatal("expected both keys to exist")
+	}
+	value, err := store.Get("expiring")
+	if err != nil || value == nil {
+		t.Fatalf("expected value before expiry, got %v (%v)", value, err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if store.Exists("expiring") {
+		t.Error("expected key to expire")
+	}
+	if value, _ := store.Get("expiring"); value != nil {
+		t.Errorf("expected no value after expiry, got %v", value)
+	}
+	if value, _ := store.Get("persistent"); value == nil {
+		t.Error("expected key without ttl to persist")
+	}
+
+	if err := store.Delete("persistent"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if value, err := store.Get("persistent"); value != nil || err != nil {
+		t.Errorf("expected deleted key to be missing, got %v (%v)", value, err)
+	}
+
+	if stored, err := store.SetIfNotExists("once", "a", time.Minute); !stored || err != nil {
+		t.Errorf("expected first SetIfNotExists to store, got %v (%v)", stored, err)
+	}
+	if stored, err := store.SetIfNotExists("once", "b", time.Minute); stored || err != nil {
+		t.Errorf("expected second SetIfNotExists to be rejected, got %v (%v)", stored, err)
+	}
+	if stored, _ := store.SetIfNotExists("expiring", "value", time.Minute); !stored {
+		t.Error("expected SetIfNotExists to replace an expired value")
+	}
+
+	for _, item := range []string{"first", "second"} {
+		if err := store.Append("list", []byte(item)); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	values, err := store.List("list")
+	if err != nil || len(values) != 2 || string(values[0].([]byte)) != "first" || string(values[1].([]byte)) != "second" {
+		t.Errorf("expected list in insertion order, got %v (%v)", values, err)
+	}
+	if err := store.Append("list", []byte("third")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := store.Trim("list", 2); err != nil {
+		t.Fatalf("Trim failed: %v", err)
+	}
+	values, err = store.List("list")
+	if err != nil || len(values) != 2 || string(values[0].([]byte)) != "second" || string(values[1].([]byte)) != "third" {
+		t.Errorf("expected the oldest value to be trimmed, got %v (%v)", values, err)
+	}
+	var newest []string
+	if err := store.ListReverse("list", func(value interface{}) bool {
+		newest = append(newest, string(value.([]byte)))
+		return len(newest) < 1
+	}); err != nil || len(newest) != 1 || newest[0] != "third" {
+		t.Errorf("expected to stop after the newest value, got %v (%v)", newest, err)
+	}
+	// Trimming again after more appends only drops the values beyond keep
+	if err := store.Append("list", []byte("fourth")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := store.Trim("list", 2); err != nil {
+		t.Fatalf("Trim failed: %v", err)
+	}
+	values, err = store.List("list")
+	if err != nil || len(values) != 2 || string(values[0].([]byte)) != "third" || string(values[1].([]byte)) != "fourth" {
+		t.Errorf("expected the two newest values to be kept, got %v (%v)", values, err)
+	}
+	if err := store.Trim("list", 0); err != nil {
+		t.Fatalf("Trim failed: %v", err)
+	}
+	if values, _ := store.List("list"); len(values) != 0 {
+		t.Errorf("expected trimming to zero to empty the list, got %v", values)
+	}
+	if err := store.Delete("list"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if values, _ := store.List("list"); len(values) != 0 {
+		t.Errorf("expected deleted list to be empty, got %v", values)
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, NewMemoryStorage())
+}
+
+func TestBoltStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := NewBoltStorage(path, 0)
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	testStorage(t, store)
+
+	type record struct{ Name string }
+	if err := store.Set("record", record{Name: "a"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	// A run of adjacent expired keys must be removed in a single pass
+	expired := []string{"expired-1", "expired-2", "expired-3", "expired-4"}
+	for _, key := range expired {
+		if err := store.SetWithTTL(key, "value", time.Nanosecond); err != nil {
+			t.Fatalf("SetWithTTL failed: %v", err)
+		}
+	}
+	time.Sleep(time.Millisecond)
+	if err := store.removeExpired(); err != nil {
+		t.Fatalf("removeExpired failed: %v", err)
+	}
+	_ = store.db.View(func(tx *bolt.Tx) error {
+		for _, key := range expired {
+			if tx.Bucket(boltBucket).Get([]byte(key)) != nil {
+				t.Errorf("expected expired value %s to be removed from the file", key)
+			}
+		}
+		return nil
+	})
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Values survive reopening the file
+	store, err = NewBoltStorage(path, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	defer store.Close()
+
+	value, err := store.Get("record")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value.([]byte)) != `{"Name":"a"}` {
+		t.Errorf("expected JSON encoded record, got %s", value)
+	}
+}
+
+func TestRedisStorage_Trim(t *testing.T) {
+	server := miniredis.RunT(t)
+	store, err := NewRedisStorage(RedisConfig{Redis: redisclient.Config{Addr: server.Addr()}, KeyPrefix: "test:"})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	for _, item := range []string{"first", "second", "third"} {
+		if err := store.Append("list", item); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if err := store.Trim("list", 2); err != nil {
+		t.Fatalf("Trim failed: %v", err)
+	}
+	values, err := store.List("list")
+	if err != nil || len(values) != 2 || string(values[0].([]byte)) != "second" || string(values[1].([]byte)) != "third" {
+		t.Errorf("expected the oldest value to be trimmed, got %v (%v)", values, err)
+	}
+}
+
+func TestRedisStorage_ListReverse(t *testing.T) {
+	server := miniredis.RunT(t)
+	store, err := NewRedisStorage(RedisConfig{Redis: redisclient.Config{Addr: server.Addr()}})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	// More values than fit in one page
+	total := redisListPage + redisListPage/2
+	for i := range total {
+		if err := store.Append("list", strconv.Itoa(i)); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	var got []string
+	if err := store.ListReverse("list", func(value interface{}) bool {
+		got = append(got, string(value.([]byte)))
+		return true
+	}); err != nil {
+		t.Fatalf("ListReverse failed: %v", err)
+	}
+	if len(got) != total || got[0] != strconv.Itoa(total-1) || got[total-1] != "0" {
+		t.Errorf("expected %d values newest first, got %d from %v to %v", total, len(got), got[0], got[len(got)-1])
+	}
+}
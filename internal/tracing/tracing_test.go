@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectLink(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{}, "test"); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, webhook := Start(context.Background(), "webhook")
+	carrier := Inject(ctx)
+	webhook.End()
+
+	link, ok := Link(carrier)
+	if !ok {
+		t.Fatalf("expected a link from carrier %v", carrier)
+	}
+	_, job := Start(context.Background(), "job", trace.WithLinks(link))
+	job.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	links := spans[1].Links()
+	if len(links) != 1 || links[0].SpanContext.SpanID() != spans[0].SpanContext().SpanID() {
+		t.Errorf("expected job span to link to the webhook span, got %+v", links)
+	}
+
+	if _, ok := Link(nil); ok {
+		t.Error("expected no link without trace context")
+	}
+}